@@ -6,11 +6,20 @@ package olvm
 import (
 	"fmt"
 	"log"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
 )
 
 // Artifact is an artifact implementation that contains built template.
 type Artifact struct {
 	templateID string
+
+	// files are the downloaded OVA files and their checksum files
+	files []string
+
+	// StateData should store data such as guest inventory that can be
+	// shared with post-processors
+	StateData map[string]interface{}
 }
 
 // BuilderId uniquely identifies the builder.
@@ -18,9 +27,10 @@ func (*Artifact) BuilderId() string {
 	return BuilderID
 }
 
-// Files returns the files represented by the artifact. Not used for OLVM.
-func (*Artifact) Files() []string {
-	return nil
+// Files returns the files represented by the artifact, which are the
+// downloaded OVA files if ova_download is configured.
+func (a *Artifact) Files() []string {
+	return a.files
 }
 
 // Id returns the template identifier of the artifact.
@@ -32,9 +42,9 @@ func (a *Artifact) String() string {
 	return fmt.Sprintf("A template was created: %s", a.templateID)
 }
 
-// State returns specific details from the artifact. Not used for OLVM.
+// State returns specific details from the artifact.
 func (a *Artifact) State(name string) interface{} {
-	return nil
+	return a.StateData[name]
 }
 
 // Destroy deletes the template associated with the artifact.
@@ -43,3 +53,14 @@ func (a *Artifact) Destroy() error {
 	//TODO: Implement template deletion logic if needed
 	return nil
 }
+
+// putArtifactState records a value that the builder exposes through
+// Artifact.State once the build completes
+func putArtifactState(state multistep.StateBag, name string, value interface{}) {
+	data, ok := state.GetOk("artifact_state")
+	if !ok {
+		data = make(map[string]interface{})
+		state.Put("artifact_state", data)
+	}
+	data.(map[string]interface{})[name] = value
+}
@@ -1,4 +1,4 @@
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,TemplateRetentionConfig,TemplatePermissionConfig,PublishTargetConfig,OVAExportConfig,OVADownloadConfig,SourceSnapshotConfig,SourceOVAConfig
 
 package olvm
 
@@ -41,6 +41,12 @@ func (b *Builder) Prepare(raws ...interface{}) (generatedVars []string, warnings
 			baseName = b.config.SourceTemplateName
 		case b.config.SourceDiskName != "":
 			baseName = b.config.SourceDiskName
+		case b.config.SourceVMName != "":
+			baseName = b.config.SourceVMName
+		case b.config.SourceSnapshot != nil && b.config.SourceSnapshot.VMName != "":
+			baseName = b.config.SourceSnapshot.VMName
+		case b.config.SourceOVA != nil:
+			baseName = b.config.SourceOVA.baseName()
 		default:
 			baseName = "olvm"
 		}
@@ -70,12 +76,6 @@ func (b *Builder) Prepare(raws ...interface{}) (generatedVars []string, warnings
 		log.Printf("Using default cleanup_vm: %t", *b.config.CleanupVM)
 	}
 
-	// Set default export filename if export_host is specified but filename is not
-	if b.config.ExportHost != "" && b.config.ExportFileName == "" {
-		b.config.ExportFileName = fmt.Sprintf("%s.ova", b.config.DestinationTemplateName)
-		log.Printf("Using default export_file_name: %s", b.config.ExportFileName)
-	}
-
 	return nil, warnings, nil
 }
 
@@ -110,6 +110,9 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 		Comm:         &b.config.Comm,
 		DebugKeyPath: fmt.Sprintf("olvm_%s.pem", b.config.PackerBuildName),
 	})
+	steps = append(steps, &stepCheckDestinationTemplate{})
+	steps = append(steps, &stepCheckTemplatePermissions{})
+	steps = append(steps, &stepImportOVA{})
 	steps = append(steps, &stepCreateVM{
 		Ctx:   b.config.ctx,
 		Debug: b.config.PackerDebug,
@@ -118,6 +121,7 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 		Debug: b.config.PackerDebug,
 		Comm:  &b.config.Comm,
 	})
+	steps = append(steps, &stepWaitForGuestAgent{})
 	steps = append(steps, &communicator.StepConnect{
 		Config:    &b.config.Comm,
 		Host:      commHost,
@@ -127,14 +131,22 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 	steps = append(steps, &commonsteps.StepCleanupTempKeys{
 		Comm: &b.config.Comm,
 	})
+	steps = append(steps, &stepGeneralizeGuest{})
 	steps = append(steps, &stepStopVM{})
 	steps = append(steps, &stepCleanupInterfaces{})
+	steps = append(steps, &stepSparsifyDisks{})
 	steps = append(steps, &stepCreateTemplateFromVM{
 		Debug: b.config.PackerDebug,
 	})
+	steps = append(steps, &stepCopyTemplateDisks{})
+	steps = append(steps, &stepPublishTemplate{})
 	steps = append(steps, &stepExportTemplateToOVA{
 		Debug: b.config.PackerDebug,
 	})
+	steps = append(steps, &stepDownloadOVA{})
+	steps = append(steps, &stepExportTemplateToDomain{})
+	steps = append(steps, &stepPruneTemplates{})
+	steps = append(steps, &stepRemoveReplacedTemplates{})
 
 	defer func() {
 		if err := recover(); err != nil {
@@ -157,6 +169,15 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 
 	artifact := &Artifact{
 		templateID: templateID.(string),
+		StateData:  make(map[string]interface{}),
+	}
+
+	// Expose the details recorded by the build steps
+	if data, ok := state.GetOk("artifact_state"); ok {
+		artifact.StateData = data.(map[string]interface{})
+	}
+	if files, ok := state.GetOk("ova_download_files"); ok {
+		artifact.files = files.([]string)
 	}
 
 	return artifact, nil
@@ -10,94 +10,148 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName                *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType              *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion              *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug                    *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce                    *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError                  *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars                 map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars            []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	OlvmURLRaw                     *string           `mapstructure:"olvm_url" cty:"olvm_url" hcl:"olvm_url"`
-	TLSInsecure                    *bool             `mapstructure:"tls_insecure" cty:"tls_insecure" hcl:"tls_insecure"`
-	Username                       *string           `mapstructure:"username" cty:"username" hcl:"username"`
-	Password                       *string           `mapstructure:"password" cty:"password" hcl:"password"`
-	Cluster                        *string           `mapstructure:"cluster" cty:"cluster" hcl:"cluster"`
-	SourceTemplateName             *string           `mapstructure:"source_template_name" cty:"source_template_name" hcl:"source_template_name"`
-	SourceTemplateVersion          *int              `mapstructure:"source_template_version" cty:"source_template_version" hcl:"source_template_version"`
-	SourceTemplateID               *string           `mapstructure:"source_template_id" cty:"source_template_id" hcl:"source_template_id"`
-	SourceDiskName                 *string           `mapstructure:"source_disk_name" cty:"source_disk_name" hcl:"source_disk_name"`
-	SourceDiskID                   *string           `mapstructure:"source_disk_id" cty:"source_disk_id" hcl:"source_disk_id"`
-	Type                           *string           `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
-	PauseBeforeConnect             *string           `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
-	SSHHost                        *string           `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
-	SSHPort                        *int              `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
-	SSHUsername                    *string           `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
-	SSHPassword                    *string           `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
-	SSHKeyPairName                 *string           `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
-	SSHTemporaryKeyPairName        *string           `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
-	SSHTemporaryKeyPairType        *string           `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
-	SSHTemporaryKeyPairBits        *int              `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
-	SSHCiphers                     []string          `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
-	SSHClearAuthorizedKeys         *bool             `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
-	SSHKEXAlgos                    []string          `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
-	SSHPrivateKeyFile              *string           `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
-	SSHCertificateFile             *string           `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
-	SSHPty                         *bool             `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
-	SSHTimeout                     *string           `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
-	SSHWaitTimeout                 *string           `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
-	SSHAgentAuth                   *bool             `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
-	SSHDisableAgentForwarding      *bool             `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
-	SSHHandshakeAttempts           *int              `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
-	SSHBastionHost                 *string           `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
-	SSHBastionPort                 *int              `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
-	SSHBastionAgentAuth            *bool             `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
-	SSHBastionUsername             *string           `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
-	SSHBastionPassword             *string           `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
-	SSHBastionInteractive          *bool             `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
-	SSHBastionPrivateKeyFile       *string           `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
-	SSHBastionCertificateFile      *string           `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
-	SSHFileTransferMethod          *string           `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
-	SSHProxyHost                   *string           `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
-	SSHProxyPort                   *int              `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
-	SSHProxyUsername               *string           `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
-	SSHProxyPassword               *string           `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
-	SSHKeepAliveInterval           *string           `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
-	SSHReadWriteTimeout            *string           `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
-	SSHRemoteTunnels               []string          `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
-	SSHLocalTunnels                []string          `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
-	SSHPublicKey                   []byte            `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
-	SSHPrivateKey                  []byte            `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
-	WinRMUser                      *string           `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
-	WinRMPassword                  *string           `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
-	WinRMHost                      *string           `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
-	WinRMNoProxy                   *bool             `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
-	WinRMPort                      *int              `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
-	WinRMTimeout                   *string           `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
-	WinRMUseSSL                    *bool             `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
-	WinRMInsecure                  *bool             `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
-	WinRMUseNTLM                   *bool             `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
-	VMName                         *string           `mapstructure:"vm_name" cty:"vm_name" hcl:"vm_name"`
-	VmVcpuCount                    *int              `mapstructure:"vm_vcpu_count" cty:"vm_vcpu_count" hcl:"vm_vcpu_count"`
-	VmMemoryMB                     *int              `mapstructure:"vm_memory_mb" cty:"vm_memory_mb" hcl:"vm_memory_mb"`
-	VMStorageDriver                *string           `mapstructure:"vm_storage_driver" cty:"vm_storage_driver" hcl:"vm_storage_driver"`
-	IPAddress                      *string           `mapstructure:"address" cty:"address" hcl:"address"`
-	Netmask                        *string           `mapstructure:"netmask" cty:"netmask" hcl:"netmask"`
-	Gateway                        *string           `mapstructure:"gateway" cty:"gateway" hcl:"gateway"`
-	NetworkName                    *string           `mapstructure:"network_name" cty:"network_name" hcl:"network_name"`
-	VnicProfile                    *string           `mapstructure:"vnic_profile" cty:"vnic_profile" hcl:"vnic_profile"`
-	DNSServers                     []string          `mapstructure:"dns_servers" cty:"dns_servers" hcl:"dns_servers"`
-	OSInterfaceName                *string           `mapstructure:"os_interface_name" cty:"os_interface_name" hcl:"os_interface_name"`
-	DestinationTemplateName        *string           `mapstructure:"destination_template_name" cty:"destination_template_name" hcl:"destination_template_name"`
-	DestinationTemplateDescription *string           `mapstructure:"destination_template_description" cty:"destination_template_description" hcl:"destination_template_description"`
-	CleanupInterfaces              *bool             `mapstructure:"cleanup_interfaces" cty:"cleanup_interfaces" hcl:"cleanup_interfaces"`
-	CleanupVM                      *bool             `mapstructure:"cleanup_vm" cty:"cleanup_vm" hcl:"cleanup_vm"`
-	ExportHost                     *string           `mapstructure:"export_host" cty:"export_host" hcl:"export_host"`
-	ExportDirectory                *string           `mapstructure:"export_directory" cty:"export_directory" hcl:"export_directory"`
-	ExportFileName                 *string           `mapstructure:"export_file_name" cty:"export_file_name" hcl:"export_file_name"`
-	MaxRetries                     *int              `mapstructure:"max_retries" cty:"max_retries" hcl:"max_retries"`
-	RetryIntervalSec               *int              `mapstructure:"retry_interval_sec" cty:"retry_interval_sec" hcl:"retry_interval_sec"`
-	TemplateSeal                   *bool             `mapstructure:"template_seal" cty:"template_seal" hcl:"template_seal"`
+	PackerBuildName                *string                        `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType              *string                        `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion              *string                        `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug                    *bool                          `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce                    *bool                          `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError                  *string                        `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars                 map[string]string              `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars            []string                       `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	OlvmURLRaw                     *string                        `mapstructure:"olvm_url" cty:"olvm_url" hcl:"olvm_url"`
+	TLSInsecure                    *bool                          `mapstructure:"tls_insecure" cty:"tls_insecure" hcl:"tls_insecure"`
+	Username                       *string                        `mapstructure:"username" cty:"username" hcl:"username"`
+	Password                       *string                        `mapstructure:"password" cty:"password" hcl:"password"`
+	Cluster                        *string                        `mapstructure:"cluster" cty:"cluster" hcl:"cluster"`
+	SourceTemplateName             *string                        `mapstructure:"source_template_name" cty:"source_template_name" hcl:"source_template_name"`
+	SourceTemplateVersion          *string                        `mapstructure:"source_template_version" cty:"source_template_version" hcl:"source_template_version"`
+	SourceTemplateID               *string                        `mapstructure:"source_template_id" cty:"source_template_id" hcl:"source_template_id"`
+	DataCenter                     *string                        `mapstructure:"data_center" cty:"data_center" hcl:"data_center"`
+	SourceDiskName                 *string                        `mapstructure:"source_disk_name" cty:"source_disk_name" hcl:"source_disk_name"`
+	SourceDiskID                   *string                        `mapstructure:"source_disk_id" cty:"source_disk_id" hcl:"source_disk_id"`
+	HardwareTemplateName           *string                        `mapstructure:"hardware_template_name" cty:"hardware_template_name" hcl:"hardware_template_name"`
+	SourceVMName                   *string                        `mapstructure:"source_vm_name" cty:"source_vm_name" hcl:"source_vm_name"`
+	SourceVMID                     *string                        `mapstructure:"source_vm_id" cty:"source_vm_id" hcl:"source_vm_id"`
+	SourceVMSnapshot               *string                        `mapstructure:"source_vm_snapshot" cty:"source_vm_snapshot" hcl:"source_vm_snapshot"`
+	SourceSnapshot                 *FlatSourceSnapshotConfig      `mapstructure:"source_snapshot" cty:"source_snapshot" hcl:"source_snapshot"`
+	SourceOVA                      *FlatSourceOVAConfig           `mapstructure:"source_ova" cty:"source_ova" hcl:"source_ova"`
+	Type                           *string                        `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
+	PauseBeforeConnect             *string                        `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
+	SSHHost                        *string                        `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
+	SSHPort                        *int                           `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
+	SSHUsername                    *string                        `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
+	SSHPassword                    *string                        `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
+	SSHKeyPairName                 *string                        `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
+	SSHTemporaryKeyPairName        *string                        `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
+	SSHTemporaryKeyPairType        *string                        `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
+	SSHTemporaryKeyPairBits        *int                           `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
+	SSHCiphers                     []string                       `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
+	SSHClearAuthorizedKeys         *bool                          `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
+	SSHKEXAlgos                    []string                       `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
+	SSHPrivateKeyFile              *string                        `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
+	SSHCertificateFile             *string                        `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
+	SSHPty                         *bool                          `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
+	SSHTimeout                     *string                        `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
+	SSHWaitTimeout                 *string                        `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
+	SSHAgentAuth                   *bool                          `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
+	SSHDisableAgentForwarding      *bool                          `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
+	SSHHandshakeAttempts           *int                           `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
+	SSHBastionHost                 *string                        `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
+	SSHBastionPort                 *int                           `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
+	SSHBastionAgentAuth            *bool                          `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
+	SSHBastionUsername             *string                        `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
+	SSHBastionPassword             *string                        `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
+	SSHBastionInteractive          *bool                          `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
+	SSHBastionPrivateKeyFile       *string                        `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
+	SSHBastionCertificateFile      *string                        `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
+	SSHFileTransferMethod          *string                        `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
+	SSHProxyHost                   *string                        `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
+	SSHProxyPort                   *int                           `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
+	SSHProxyUsername               *string                        `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
+	SSHProxyPassword               *string                        `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
+	SSHKeepAliveInterval           *string                        `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
+	SSHReadWriteTimeout            *string                        `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
+	SSHRemoteTunnels               []string                       `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
+	SSHLocalTunnels                []string                       `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
+	SSHPublicKey                   []byte                         `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
+	SSHPrivateKey                  []byte                         `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
+	WinRMUser                      *string                        `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
+	WinRMPassword                  *string                        `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
+	WinRMHost                      *string                        `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
+	WinRMNoProxy                   *bool                          `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
+	WinRMPort                      *int                           `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
+	WinRMTimeout                   *string                        `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
+	WinRMUseSSL                    *bool                          `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
+	WinRMInsecure                  *bool                          `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
+	WinRMUseNTLM                   *bool                          `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
+	ShutdownCommand                *string                        `mapstructure:"shutdown_command" required:"false" cty:"shutdown_command" hcl:"shutdown_command"`
+	ShutdownTimeout                *string                        `mapstructure:"shutdown_timeout" required:"false" cty:"shutdown_timeout" hcl:"shutdown_timeout"`
+	VMName                         *string                        `mapstructure:"vm_name" cty:"vm_name" hcl:"vm_name"`
+	VmVcpuCount                    *int                           `mapstructure:"vm_vcpu_count" cty:"vm_vcpu_count" hcl:"vm_vcpu_count"`
+	VmMemoryMB                     *int                           `mapstructure:"vm_memory_mb" cty:"vm_memory_mb" hcl:"vm_memory_mb"`
+	VMStorageDriver                *string                        `mapstructure:"vm_storage_driver" cty:"vm_storage_driver" hcl:"vm_storage_driver"`
+	InstanceType                   *string                        `mapstructure:"instance_type" cty:"instance_type" hcl:"instance_type"`
+	OSType                         *string                        `mapstructure:"os_type" cty:"os_type" hcl:"os_type"`
+	VMType                         *string                        `mapstructure:"vm_type" cty:"vm_type" hcl:"vm_type"`
+	CustomProperties               map[string]string              `mapstructure:"custom_properties" cty:"custom_properties" hcl:"custom_properties"`
+	BootDevices                    []string                       `mapstructure:"boot_devices" cty:"boot_devices" hcl:"boot_devices"`
+	IOThreads                      *int                           `mapstructure:"io_threads" cty:"io_threads" hcl:"io_threads"`
+	DiskCacheMode                  *string                        `mapstructure:"disk_cache_mode" cty:"disk_cache_mode" hcl:"disk_cache_mode"`
+	Host                           *string                        `mapstructure:"host" cty:"host" hcl:"host"`
+	Hosts                          []string                       `mapstructure:"hosts" cty:"hosts" hcl:"hosts"`
+	PlacementAffinity              *string                        `mapstructure:"placement_affinity" cty:"placement_affinity" hcl:"placement_affinity"`
+	PlaceOnExportHost              *bool                          `mapstructure:"place_on_export_host" cty:"place_on_export_host" hcl:"place_on_export_host"`
+	AffinityGroups                 []string                       `mapstructure:"affinity_groups" cty:"affinity_groups" hcl:"affinity_groups"`
+	AffinityLabels                 []string                       `mapstructure:"affinity_labels" cty:"affinity_labels" hcl:"affinity_labels"`
+	VMTags                         []string                       `mapstructure:"vm_tags" cty:"vm_tags" hcl:"vm_tags"`
+	Quota                          *string                        `mapstructure:"quota" cty:"quota" hcl:"quota"`
+	DiskProfile                    *string                        `mapstructure:"disk_profile" cty:"disk_profile" hcl:"disk_profile"`
+	CPUProfile                     *string                        `mapstructure:"cpu_profile" cty:"cpu_profile" hcl:"cpu_profile"`
+	IPAddress                      *string                        `mapstructure:"address" cty:"address" hcl:"address"`
+	Netmask                        *string                        `mapstructure:"netmask" cty:"netmask" hcl:"netmask"`
+	Gateway                        *string                        `mapstructure:"gateway" cty:"gateway" hcl:"gateway"`
+	NetworkName                    *string                        `mapstructure:"network_name" cty:"network_name" hcl:"network_name"`
+	VnicProfile                    *string                        `mapstructure:"vnic_profile" cty:"vnic_profile" hcl:"vnic_profile"`
+	DNSServers                     []string                       `mapstructure:"dns_servers" cty:"dns_servers" hcl:"dns_servers"`
+	OSInterfaceName                *string                        `mapstructure:"os_interface_name" cty:"os_interface_name" hcl:"os_interface_name"`
+	DestinationTemplateName        *string                        `mapstructure:"destination_template_name" cty:"destination_template_name" hcl:"destination_template_name"`
+	DestinationTemplateDescription *string                        `mapstructure:"destination_template_description" cty:"destination_template_description" hcl:"destination_template_description"`
+	CleanupInterfaces              *bool                          `mapstructure:"cleanup_interfaces" cty:"cleanup_interfaces" hcl:"cleanup_interfaces"`
+	CleanupVM                      *bool                          `mapstructure:"cleanup_vm" cty:"cleanup_vm" hcl:"cleanup_vm"`
+	ExportHost                     *string                        `mapstructure:"export_host" cty:"export_host" hcl:"export_host"`
+	ExportDirectory                *string                        `mapstructure:"export_directory" cty:"export_directory" hcl:"export_directory"`
+	ExportFileName                 *string                        `mapstructure:"export_file_name" cty:"export_file_name" hcl:"export_file_name"`
+	ExportStorageDomain            *string                        `mapstructure:"export_storage_domain" cty:"export_storage_domain" hcl:"export_storage_domain"`
+	ExportStorageDomainOverwrite   *bool                          `mapstructure:"export_storage_domain_overwrite" cty:"export_storage_domain_overwrite" hcl:"export_storage_domain_overwrite"`
+	ExportStorageDomainDetach      *bool                          `mapstructure:"export_storage_domain_detach" cty:"export_storage_domain_detach" hcl:"export_storage_domain_detach"`
+	WaitForGuestAgent              *bool                          `mapstructure:"wait_for_guest_agent" cty:"wait_for_guest_agent" hcl:"wait_for_guest_agent"`
+	GuestAgentTimeout              *string                        `mapstructure:"guest_agent_timeout" cty:"guest_agent_timeout" hcl:"guest_agent_timeout"`
+	Sparsify                       *bool                          `mapstructure:"sparsify" cty:"sparsify" hcl:"sparsify"`
+	Generalize                     *bool                          `mapstructure:"generalize" cty:"generalize" hcl:"generalize"`
+	GeneralizeOperations           []string                       `mapstructure:"generalize_operations" cty:"generalize_operations" hcl:"generalize_operations"`
+	MaxRetries                     *int                           `mapstructure:"max_retries" cty:"max_retries" hcl:"max_retries"`
+	RetryIntervalSec               *int                           `mapstructure:"retry_interval_sec" cty:"retry_interval_sec" hcl:"retry_interval_sec"`
+	JobTimeout                     *string                        `mapstructure:"job_timeout" cty:"job_timeout" hcl:"job_timeout"`
+	TemplateSeal                   *bool                          `mapstructure:"template_seal" cty:"template_seal" hcl:"template_seal"`
+	BaseTemplateName               *string                        `mapstructure:"base_template_name" cty:"base_template_name" hcl:"base_template_name"`
+	TemplateVersionName            *string                        `mapstructure:"template_version_name" cty:"template_version_name" hcl:"template_version_name"`
+	OnTemplateExists               *string                        `mapstructure:"on_template_exists" cty:"on_template_exists" hcl:"on_template_exists"`
+	TemplateHighAvailability       *bool                          `mapstructure:"template_high_availability" cty:"template_high_availability" hcl:"template_high_availability"`
+	TemplateHAPriority             *int                           `mapstructure:"template_ha_priority" cty:"template_ha_priority" hcl:"template_ha_priority"`
+	TemplateLeaseStorageDomain     *string                        `mapstructure:"template_lease_storage_domain" cty:"template_lease_storage_domain" hcl:"template_lease_storage_domain"`
+	TemplateMigrationPolicy        *string                        `mapstructure:"template_migration_policy" cty:"template_migration_policy" hcl:"template_migration_policy"`
+	TemplateMigrationDowntimeMs    *int                           `mapstructure:"template_migration_downtime_ms" cty:"template_migration_downtime_ms" hcl:"template_migration_downtime_ms"`
+	TemplateStateless              *bool                          `mapstructure:"template_stateless" cty:"template_stateless" hcl:"template_stateless"`
+	TemplateDeleteProtected        *bool                          `mapstructure:"template_delete_protected" cty:"template_delete_protected" hcl:"template_delete_protected"`
+	TemplateTags                   []string                       `mapstructure:"template_tags" cty:"template_tags" hcl:"template_tags"`
+	TemplateCopyStorageDomains     []string                       `mapstructure:"template_copy_storage_domains" cty:"template_copy_storage_domains" hcl:"template_copy_storage_domains"`
+	PublishExportDomain            *string                        `mapstructure:"publish_export_domain" cty:"publish_export_domain" hcl:"publish_export_domain"`
+	PublishExportDomainDetach      *bool                          `mapstructure:"publish_export_domain_detach" cty:"publish_export_domain_detach" hcl:"publish_export_domain_detach"`
+	TemplateRetention              *FlatTemplateRetentionConfig   `mapstructure:"template_retention" cty:"template_retention" hcl:"template_retention"`
+	TemplatePermissions            []FlatTemplatePermissionConfig `mapstructure:"template_permissions" cty:"template_permissions" hcl:"template_permissions"`
+	PublishTargets                 []FlatPublishTargetConfig      `mapstructure:"publish_target" cty:"publish_target" hcl:"publish_target"`
+	OVAExports                     []FlatOVAExportConfig          `mapstructure:"ova_export" cty:"ova_export" hcl:"ova_export"`
+	OVADownload                    *FlatOVADownloadConfig         `mapstructure:"ova_download" cty:"ova_download" hcl:"ova_download"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -126,10 +180,17 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"password":                         &hcldec.AttrSpec{Name: "password", Type: cty.String, Required: false},
 		"cluster":                          &hcldec.AttrSpec{Name: "cluster", Type: cty.String, Required: false},
 		"source_template_name":             &hcldec.AttrSpec{Name: "source_template_name", Type: cty.String, Required: false},
-		"source_template_version":          &hcldec.AttrSpec{Name: "source_template_version", Type: cty.Number, Required: false},
+		"source_template_version":          &hcldec.AttrSpec{Name: "source_template_version", Type: cty.String, Required: false},
 		"source_template_id":               &hcldec.AttrSpec{Name: "source_template_id", Type: cty.String, Required: false},
+		"data_center":                      &hcldec.AttrSpec{Name: "data_center", Type: cty.String, Required: false},
 		"source_disk_name":                 &hcldec.AttrSpec{Name: "source_disk_name", Type: cty.String, Required: false},
 		"source_disk_id":                   &hcldec.AttrSpec{Name: "source_disk_id", Type: cty.String, Required: false},
+		"hardware_template_name":           &hcldec.AttrSpec{Name: "hardware_template_name", Type: cty.String, Required: false},
+		"source_vm_name":                   &hcldec.AttrSpec{Name: "source_vm_name", Type: cty.String, Required: false},
+		"source_vm_id":                     &hcldec.AttrSpec{Name: "source_vm_id", Type: cty.String, Required: false},
+		"source_vm_snapshot":               &hcldec.AttrSpec{Name: "source_vm_snapshot", Type: cty.String, Required: false},
+		"source_snapshot":                  &hcldec.BlockSpec{TypeName: "source_snapshot", Nested: hcldec.ObjectSpec((*FlatSourceSnapshotConfig)(nil).HCL2Spec())},
+		"source_ova":                       &hcldec.BlockSpec{TypeName: "source_ova", Nested: hcldec.ObjectSpec((*FlatSourceOVAConfig)(nil).HCL2Spec())},
 		"communicator":                     &hcldec.AttrSpec{Name: "communicator", Type: cty.String, Required: false},
 		"pause_before_connecting":          &hcldec.AttrSpec{Name: "pause_before_connecting", Type: cty.String, Required: false},
 		"ssh_host":                         &hcldec.AttrSpec{Name: "ssh_host", Type: cty.String, Required: false},
@@ -179,10 +240,29 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"winrm_use_ssl":                    &hcldec.AttrSpec{Name: "winrm_use_ssl", Type: cty.Bool, Required: false},
 		"winrm_insecure":                   &hcldec.AttrSpec{Name: "winrm_insecure", Type: cty.Bool, Required: false},
 		"winrm_use_ntlm":                   &hcldec.AttrSpec{Name: "winrm_use_ntlm", Type: cty.Bool, Required: false},
+		"shutdown_command":                 &hcldec.AttrSpec{Name: "shutdown_command", Type: cty.String, Required: false},
+		"shutdown_timeout":                 &hcldec.AttrSpec{Name: "shutdown_timeout", Type: cty.String, Required: false},
 		"vm_name":                          &hcldec.AttrSpec{Name: "vm_name", Type: cty.String, Required: false},
 		"vm_vcpu_count":                    &hcldec.AttrSpec{Name: "vm_vcpu_count", Type: cty.Number, Required: false},
 		"vm_memory_mb":                     &hcldec.AttrSpec{Name: "vm_memory_mb", Type: cty.Number, Required: false},
 		"vm_storage_driver":                &hcldec.AttrSpec{Name: "vm_storage_driver", Type: cty.String, Required: false},
+		"instance_type":                    &hcldec.AttrSpec{Name: "instance_type", Type: cty.String, Required: false},
+		"os_type":                          &hcldec.AttrSpec{Name: "os_type", Type: cty.String, Required: false},
+		"vm_type":                          &hcldec.AttrSpec{Name: "vm_type", Type: cty.String, Required: false},
+		"custom_properties":                &hcldec.AttrSpec{Name: "custom_properties", Type: cty.Map(cty.String), Required: false},
+		"boot_devices":                     &hcldec.AttrSpec{Name: "boot_devices", Type: cty.List(cty.String), Required: false},
+		"io_threads":                       &hcldec.AttrSpec{Name: "io_threads", Type: cty.Number, Required: false},
+		"disk_cache_mode":                  &hcldec.AttrSpec{Name: "disk_cache_mode", Type: cty.String, Required: false},
+		"host":                             &hcldec.AttrSpec{Name: "host", Type: cty.String, Required: false},
+		"hosts":                            &hcldec.AttrSpec{Name: "hosts", Type: cty.List(cty.String), Required: false},
+		"placement_affinity":               &hcldec.AttrSpec{Name: "placement_affinity", Type: cty.String, Required: false},
+		"place_on_export_host":             &hcldec.AttrSpec{Name: "place_on_export_host", Type: cty.Bool, Required: false},
+		"affinity_groups":                  &hcldec.AttrSpec{Name: "affinity_groups", Type: cty.List(cty.String), Required: false},
+		"affinity_labels":                  &hcldec.AttrSpec{Name: "affinity_labels", Type: cty.List(cty.String), Required: false},
+		"vm_tags":                          &hcldec.AttrSpec{Name: "vm_tags", Type: cty.List(cty.String), Required: false},
+		"quota":                            &hcldec.AttrSpec{Name: "quota", Type: cty.String, Required: false},
+		"disk_profile":                     &hcldec.AttrSpec{Name: "disk_profile", Type: cty.String, Required: false},
+		"cpu_profile":                      &hcldec.AttrSpec{Name: "cpu_profile", Type: cty.String, Required: false},
 		"address":                          &hcldec.AttrSpec{Name: "address", Type: cty.String, Required: false},
 		"netmask":                          &hcldec.AttrSpec{Name: "netmask", Type: cty.String, Required: false},
 		"gateway":                          &hcldec.AttrSpec{Name: "gateway", Type: cty.String, Required: false},
@@ -197,9 +277,242 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"export_host":                      &hcldec.AttrSpec{Name: "export_host", Type: cty.String, Required: false},
 		"export_directory":                 &hcldec.AttrSpec{Name: "export_directory", Type: cty.String, Required: false},
 		"export_file_name":                 &hcldec.AttrSpec{Name: "export_file_name", Type: cty.String, Required: false},
+		"export_storage_domain":            &hcldec.AttrSpec{Name: "export_storage_domain", Type: cty.String, Required: false},
+		"export_storage_domain_overwrite":  &hcldec.AttrSpec{Name: "export_storage_domain_overwrite", Type: cty.Bool, Required: false},
+		"export_storage_domain_detach":     &hcldec.AttrSpec{Name: "export_storage_domain_detach", Type: cty.Bool, Required: false},
+		"wait_for_guest_agent":             &hcldec.AttrSpec{Name: "wait_for_guest_agent", Type: cty.Bool, Required: false},
+		"guest_agent_timeout":              &hcldec.AttrSpec{Name: "guest_agent_timeout", Type: cty.String, Required: false},
+		"sparsify":                         &hcldec.AttrSpec{Name: "sparsify", Type: cty.Bool, Required: false},
+		"generalize":                       &hcldec.AttrSpec{Name: "generalize", Type: cty.Bool, Required: false},
+		"generalize_operations":            &hcldec.AttrSpec{Name: "generalize_operations", Type: cty.List(cty.String), Required: false},
 		"max_retries":                      &hcldec.AttrSpec{Name: "max_retries", Type: cty.Number, Required: false},
 		"retry_interval_sec":               &hcldec.AttrSpec{Name: "retry_interval_sec", Type: cty.Number, Required: false},
+		"job_timeout":                      &hcldec.AttrSpec{Name: "job_timeout", Type: cty.String, Required: false},
 		"template_seal":                    &hcldec.AttrSpec{Name: "template_seal", Type: cty.Bool, Required: false},
+		"base_template_name":               &hcldec.AttrSpec{Name: "base_template_name", Type: cty.String, Required: false},
+		"template_version_name":            &hcldec.AttrSpec{Name: "template_version_name", Type: cty.String, Required: false},
+		"on_template_exists":               &hcldec.AttrSpec{Name: "on_template_exists", Type: cty.String, Required: false},
+		"template_high_availability":       &hcldec.AttrSpec{Name: "template_high_availability", Type: cty.Bool, Required: false},
+		"template_ha_priority":             &hcldec.AttrSpec{Name: "template_ha_priority", Type: cty.Number, Required: false},
+		"template_lease_storage_domain":    &hcldec.AttrSpec{Name: "template_lease_storage_domain", Type: cty.String, Required: false},
+		"template_migration_policy":        &hcldec.AttrSpec{Name: "template_migration_policy", Type: cty.String, Required: false},
+		"template_migration_downtime_ms":   &hcldec.AttrSpec{Name: "template_migration_downtime_ms", Type: cty.Number, Required: false},
+		"template_stateless":               &hcldec.AttrSpec{Name: "template_stateless", Type: cty.Bool, Required: false},
+		"template_delete_protected":        &hcldec.AttrSpec{Name: "template_delete_protected", Type: cty.Bool, Required: false},
+		"template_tags":                    &hcldec.AttrSpec{Name: "template_tags", Type: cty.List(cty.String), Required: false},
+		"template_copy_storage_domains":    &hcldec.AttrSpec{Name: "template_copy_storage_domains", Type: cty.List(cty.String), Required: false},
+		"publish_export_domain":            &hcldec.AttrSpec{Name: "publish_export_domain", Type: cty.String, Required: false},
+		"publish_export_domain_detach":     &hcldec.AttrSpec{Name: "publish_export_domain_detach", Type: cty.Bool, Required: false},
+		"template_retention":               &hcldec.BlockSpec{TypeName: "template_retention", Nested: hcldec.ObjectSpec((*FlatTemplateRetentionConfig)(nil).HCL2Spec())},
+		"template_permissions":             &hcldec.BlockListSpec{TypeName: "template_permissions", Nested: hcldec.ObjectSpec((*FlatTemplatePermissionConfig)(nil).HCL2Spec())},
+		"publish_target":                   &hcldec.BlockListSpec{TypeName: "publish_target", Nested: hcldec.ObjectSpec((*FlatPublishTargetConfig)(nil).HCL2Spec())},
+		"ova_export":                       &hcldec.BlockListSpec{TypeName: "ova_export", Nested: hcldec.ObjectSpec((*FlatOVAExportConfig)(nil).HCL2Spec())},
+		"ova_download":                     &hcldec.BlockSpec{TypeName: "ova_download", Nested: hcldec.ObjectSpec((*FlatOVADownloadConfig)(nil).HCL2Spec())},
+	}
+	return s
+}
+
+// FlatOVADownloadConfig is an auto-generated flat version of OVADownloadConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatOVADownloadConfig struct {
+	Username              *string `mapstructure:"username" cty:"username" hcl:"username"`
+	Password              *string `mapstructure:"password" cty:"password" hcl:"password"`
+	PrivateKeyFile        *string `mapstructure:"private_key_file" cty:"private_key_file" hcl:"private_key_file"`
+	Port                  *int    `mapstructure:"port" cty:"port" hcl:"port"`
+	KnownHostsFile        *string `mapstructure:"known_hosts_file" cty:"known_hosts_file" hcl:"known_hosts_file"`
+	InsecureIgnoreHostKey *bool   `mapstructure:"insecure_ignore_host_key" cty:"insecure_ignore_host_key" hcl:"insecure_ignore_host_key"`
+	OutputDirectory       *string `mapstructure:"output_directory" cty:"output_directory" hcl:"output_directory"`
+}
+
+// FlatMapstructure returns a new FlatOVADownloadConfig.
+// FlatOVADownloadConfig is an auto-generated flat version of OVADownloadConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*OVADownloadConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatOVADownloadConfig)
+}
+
+// HCL2Spec returns the hcl spec of a OVADownloadConfig.
+// This spec is used by HCL to read the fields of OVADownloadConfig.
+// The decoded values from this spec will then be applied to a FlatOVADownloadConfig.
+func (*FlatOVADownloadConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"username":                 &hcldec.AttrSpec{Name: "username", Type: cty.String, Required: false},
+		"password":                 &hcldec.AttrSpec{Name: "password", Type: cty.String, Required: false},
+		"private_key_file":         &hcldec.AttrSpec{Name: "private_key_file", Type: cty.String, Required: false},
+		"port":                     &hcldec.AttrSpec{Name: "port", Type: cty.Number, Required: false},
+		"known_hosts_file":         &hcldec.AttrSpec{Name: "known_hosts_file", Type: cty.String, Required: false},
+		"insecure_ignore_host_key": &hcldec.AttrSpec{Name: "insecure_ignore_host_key", Type: cty.Bool, Required: false},
+		"output_directory":         &hcldec.AttrSpec{Name: "output_directory", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatOVAExportConfig is an auto-generated flat version of OVAExportConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatOVAExportConfig struct {
+	Host      *string `mapstructure:"host" cty:"host" hcl:"host"`
+	Directory *string `mapstructure:"directory" cty:"directory" hcl:"directory"`
+	FileName  *string `mapstructure:"file_name" cty:"file_name" hcl:"file_name"`
+}
+
+// FlatMapstructure returns a new FlatOVAExportConfig.
+// FlatOVAExportConfig is an auto-generated flat version of OVAExportConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*OVAExportConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatOVAExportConfig)
+}
+
+// HCL2Spec returns the hcl spec of a OVAExportConfig.
+// This spec is used by HCL to read the fields of OVAExportConfig.
+// The decoded values from this spec will then be applied to a FlatOVAExportConfig.
+func (*FlatOVAExportConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"host":      &hcldec.AttrSpec{Name: "host", Type: cty.String, Required: false},
+		"directory": &hcldec.AttrSpec{Name: "directory", Type: cty.String, Required: false},
+		"file_name": &hcldec.AttrSpec{Name: "file_name", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatPublishTargetConfig is an auto-generated flat version of PublishTargetConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatPublishTargetConfig struct {
+	DataCenter    *string `mapstructure:"data_center" cty:"data_center" hcl:"data_center"`
+	Cluster       *string `mapstructure:"cluster" cty:"cluster" hcl:"cluster"`
+	StorageDomain *string `mapstructure:"storage_domain" cty:"storage_domain" hcl:"storage_domain"`
+}
+
+// FlatMapstructure returns a new FlatPublishTargetConfig.
+// FlatPublishTargetConfig is an auto-generated flat version of PublishTargetConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*PublishTargetConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatPublishTargetConfig)
+}
+
+// HCL2Spec returns the hcl spec of a PublishTargetConfig.
+// This spec is used by HCL to read the fields of PublishTargetConfig.
+// The decoded values from this spec will then be applied to a FlatPublishTargetConfig.
+func (*FlatPublishTargetConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"data_center":    &hcldec.AttrSpec{Name: "data_center", Type: cty.String, Required: false},
+		"cluster":        &hcldec.AttrSpec{Name: "cluster", Type: cty.String, Required: false},
+		"storage_domain": &hcldec.AttrSpec{Name: "storage_domain", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatSourceOVAConfig is an auto-generated flat version of SourceOVAConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatSourceOVAConfig struct {
+	Host          *string `mapstructure:"host" cty:"host" hcl:"host"`
+	Path          *string `mapstructure:"path" cty:"path" hcl:"path"`
+	StorageDomain *string `mapstructure:"storage_domain" cty:"storage_domain" hcl:"storage_domain"`
+	Sparse        *bool   `mapstructure:"sparse" cty:"sparse" hcl:"sparse"`
+}
+
+// FlatMapstructure returns a new FlatSourceOVAConfig.
+// FlatSourceOVAConfig is an auto-generated flat version of SourceOVAConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*SourceOVAConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatSourceOVAConfig)
+}
+
+// HCL2Spec returns the hcl spec of a SourceOVAConfig.
+// This spec is used by HCL to read the fields of SourceOVAConfig.
+// The decoded values from this spec will then be applied to a FlatSourceOVAConfig.
+func (*FlatSourceOVAConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"host":           &hcldec.AttrSpec{Name: "host", Type: cty.String, Required: false},
+		"path":           &hcldec.AttrSpec{Name: "path", Type: cty.String, Required: false},
+		"storage_domain": &hcldec.AttrSpec{Name: "storage_domain", Type: cty.String, Required: false},
+		"sparse":         &hcldec.AttrSpec{Name: "sparse", Type: cty.Bool, Required: false},
+	}
+	return s
+}
+
+// FlatSourceSnapshotConfig is an auto-generated flat version of SourceSnapshotConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatSourceSnapshotConfig struct {
+	VMName      *string `mapstructure:"vm_name" cty:"vm_name" hcl:"vm_name"`
+	VMID        *string `mapstructure:"vm_id" cty:"vm_id" hcl:"vm_id"`
+	Description *string `mapstructure:"description" cty:"description" hcl:"description"`
+	ID          *string `mapstructure:"id" cty:"id" hcl:"id"`
+}
+
+// FlatMapstructure returns a new FlatSourceSnapshotConfig.
+// FlatSourceSnapshotConfig is an auto-generated flat version of SourceSnapshotConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*SourceSnapshotConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatSourceSnapshotConfig)
+}
+
+// HCL2Spec returns the hcl spec of a SourceSnapshotConfig.
+// This spec is used by HCL to read the fields of SourceSnapshotConfig.
+// The decoded values from this spec will then be applied to a FlatSourceSnapshotConfig.
+func (*FlatSourceSnapshotConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"vm_name":     &hcldec.AttrSpec{Name: "vm_name", Type: cty.String, Required: false},
+		"vm_id":       &hcldec.AttrSpec{Name: "vm_id", Type: cty.String, Required: false},
+		"description": &hcldec.AttrSpec{Name: "description", Type: cty.String, Required: false},
+		"id":          &hcldec.AttrSpec{Name: "id", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatTemplatePermissionConfig is an auto-generated flat version of TemplatePermissionConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatTemplatePermissionConfig struct {
+	User  *string `mapstructure:"user" cty:"user" hcl:"user"`
+	Group *string `mapstructure:"group" cty:"group" hcl:"group"`
+	Role  *string `mapstructure:"role" cty:"role" hcl:"role"`
+}
+
+// FlatMapstructure returns a new FlatTemplatePermissionConfig.
+// FlatTemplatePermissionConfig is an auto-generated flat version of TemplatePermissionConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*TemplatePermissionConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatTemplatePermissionConfig)
+}
+
+// HCL2Spec returns the hcl spec of a TemplatePermissionConfig.
+// This spec is used by HCL to read the fields of TemplatePermissionConfig.
+// The decoded values from this spec will then be applied to a FlatTemplatePermissionConfig.
+func (*FlatTemplatePermissionConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"user":  &hcldec.AttrSpec{Name: "user", Type: cty.String, Required: false},
+		"group": &hcldec.AttrSpec{Name: "group", Type: cty.String, Required: false},
+		"role":  &hcldec.AttrSpec{Name: "role", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatTemplateRetentionConfig is an auto-generated flat version of TemplateRetentionConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatTemplateRetentionConfig struct {
+	NamePrefix *string `mapstructure:"name_prefix" cty:"name_prefix" hcl:"name_prefix"`
+	Tag        *string `mapstructure:"tag" cty:"tag" hcl:"tag"`
+	KeepLast   *int    `mapstructure:"keep_last" cty:"keep_last" hcl:"keep_last"`
+	MaxAge     *string `mapstructure:"max_age" cty:"max_age" hcl:"max_age"`
+	DryRun     *bool   `mapstructure:"dry_run" cty:"dry_run" hcl:"dry_run"`
+}
+
+// FlatMapstructure returns a new FlatTemplateRetentionConfig.
+// FlatTemplateRetentionConfig is an auto-generated flat version of TemplateRetentionConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*TemplateRetentionConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatTemplateRetentionConfig)
+}
+
+// HCL2Spec returns the hcl spec of a TemplateRetentionConfig.
+// This spec is used by HCL to read the fields of TemplateRetentionConfig.
+// The decoded values from this spec will then be applied to a FlatTemplateRetentionConfig.
+func (*FlatTemplateRetentionConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name_prefix": &hcldec.AttrSpec{Name: "name_prefix", Type: cty.String, Required: false},
+		"tag":         &hcldec.AttrSpec{Name: "tag", Type: cty.String, Required: false},
+		"keep_last":   &hcldec.AttrSpec{Name: "keep_last", Type: cty.Number, Required: false},
+		"max_age":     &hcldec.AttrSpec{Name: "max_age", Type: cty.String, Required: false},
+		"dry_run":     &hcldec.AttrSpec{Name: "dry_run", Type: cty.Bool, Required: false},
 	}
 	return s
 }
@@ -4,16 +4,28 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	googleuuid "github.com/google/uuid"
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/shutdowncommand"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/hashicorp/packer-plugin-sdk/uuid"
 )
 
+// migrationPolicyIDs maps the names of the engine's built-in migration
+// policies to their IDs
+var migrationPolicyIDs = map[string]string{
+	"legacy":           "00000000-0000-0000-0000-000000000000",
+	"minimal_downtime": "80554327-0569-496b-bdeb-fcbbf52b827b",
+	"suspend_workload": "80554327-0569-496b-bdeb-fcbbf52b827c",
+	"post_copy":        "a7aeedb2-8d66-4e51-bb22-32595027ce71",
+}
+
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
@@ -22,27 +34,78 @@ type Config struct {
 
 	Comm communicator.Config `mapstructure:",squash"`
 
-	VMName                         string   `mapstructure:"vm_name"`
-	VmVcpuCount                    int      `mapstructure:"vm_vcpu_count"`
-	VmMemoryMB                     int      `mapstructure:"vm_memory_mb"`
-	VMStorageDriver                string   `mapstructure:"vm_storage_driver"`
-	IPAddress                      string   `mapstructure:"address"`
-	Netmask                        string   `mapstructure:"netmask"`
-	Gateway                        string   `mapstructure:"gateway"`
-	NetworkName                    string   `mapstructure:"network_name"`
-	VnicProfile                    string   `mapstructure:"vnic_profile"`
-	DNSServers                     []string `mapstructure:"dns_servers"`
-	OSInterfaceName                string   `mapstructure:"os_interface_name"`
-	DestinationTemplateName        string   `mapstructure:"destination_template_name"`
-	DestinationTemplateDescription string   `mapstructure:"destination_template_description"`
-	CleanupInterfaces              *bool    `mapstructure:"cleanup_interfaces"`
-	CleanupVM                      *bool    `mapstructure:"cleanup_vm"`
-	ExportHost                     string   `mapstructure:"export_host"`
-	ExportDirectory                string   `mapstructure:"export_directory"`
-	ExportFileName                 string   `mapstructure:"export_file_name"`
-	MaxRetries                     int      `mapstructure:"max_retries"`
-	RetryIntervalSec               int      `mapstructure:"retry_interval_sec"`
-	TemplateSeal                   *bool    `mapstructure:"template_seal"`
+	shutdowncommand.ShutdownConfig `mapstructure:",squash"`
+
+	VMName                         string            `mapstructure:"vm_name"`
+	VmVcpuCount                    int               `mapstructure:"vm_vcpu_count"`
+	VmMemoryMB                     int               `mapstructure:"vm_memory_mb"`
+	VMStorageDriver                string            `mapstructure:"vm_storage_driver"`
+	InstanceType                   string            `mapstructure:"instance_type"`
+	OSType                         string            `mapstructure:"os_type"`
+	VMType                         string            `mapstructure:"vm_type"`
+	CustomProperties               map[string]string `mapstructure:"custom_properties"`
+	BootDevices                    []string          `mapstructure:"boot_devices"`
+	IOThreads                      int               `mapstructure:"io_threads"`
+	DiskCacheMode                  string            `mapstructure:"disk_cache_mode"`
+	Host                           string            `mapstructure:"host"`
+	Hosts                          []string          `mapstructure:"hosts"`
+	PlacementAffinity              string            `mapstructure:"placement_affinity"`
+	PlaceOnExportHost              bool              `mapstructure:"place_on_export_host"`
+	AffinityGroups                 []string          `mapstructure:"affinity_groups"`
+	AffinityLabels                 []string          `mapstructure:"affinity_labels"`
+	VMTags                         []string          `mapstructure:"vm_tags"`
+	Quota                          string            `mapstructure:"quota"`
+	DiskProfile                    string            `mapstructure:"disk_profile"`
+	CPUProfile                     string            `mapstructure:"cpu_profile"`
+	IPAddress                      string            `mapstructure:"address"`
+	Netmask                        string            `mapstructure:"netmask"`
+	Gateway                        string            `mapstructure:"gateway"`
+	NetworkName                    string            `mapstructure:"network_name"`
+	VnicProfile                    string            `mapstructure:"vnic_profile"`
+	DNSServers                     []string          `mapstructure:"dns_servers"`
+	OSInterfaceName                string            `mapstructure:"os_interface_name"`
+	DestinationTemplateName        string            `mapstructure:"destination_template_name"`
+	DestinationTemplateDescription string            `mapstructure:"destination_template_description"`
+	CleanupInterfaces              *bool             `mapstructure:"cleanup_interfaces"`
+	CleanupVM                      *bool             `mapstructure:"cleanup_vm"`
+	ExportHost                     string            `mapstructure:"export_host"`
+	ExportDirectory                string            `mapstructure:"export_directory"`
+	ExportFileName                 string            `mapstructure:"export_file_name"`
+	ExportStorageDomain            string            `mapstructure:"export_storage_domain"`
+	ExportStorageDomainOverwrite   bool              `mapstructure:"export_storage_domain_overwrite"`
+	ExportStorageDomainDetach      bool              `mapstructure:"export_storage_domain_detach"`
+	WaitForGuestAgent              bool              `mapstructure:"wait_for_guest_agent"`
+	GuestAgentTimeout              time.Duration     `mapstructure:"guest_agent_timeout"`
+	Sparsify                       bool              `mapstructure:"sparsify"`
+	Generalize                     bool              `mapstructure:"generalize"`
+	GeneralizeOperations           []string          `mapstructure:"generalize_operations"`
+	MaxRetries                     int               `mapstructure:"max_retries"`
+	RetryIntervalSec               int               `mapstructure:"retry_interval_sec"`
+	JobTimeout                     time.Duration     `mapstructure:"job_timeout"`
+	TemplateSeal                   *bool             `mapstructure:"template_seal"`
+	BaseTemplateName               string            `mapstructure:"base_template_name"`
+	TemplateVersionName            string            `mapstructure:"template_version_name"`
+	OnTemplateExists               string            `mapstructure:"on_template_exists"`
+	TemplateHighAvailability       bool              `mapstructure:"template_high_availability"`
+	TemplateHAPriority             int               `mapstructure:"template_ha_priority"`
+	TemplateLeaseStorageDomain     string            `mapstructure:"template_lease_storage_domain"`
+	TemplateMigrationPolicy        string            `mapstructure:"template_migration_policy"`
+	TemplateMigrationDowntimeMs    int               `mapstructure:"template_migration_downtime_ms"`
+	TemplateStateless              bool              `mapstructure:"template_stateless"`
+	TemplateDeleteProtected        bool              `mapstructure:"template_delete_protected"`
+	TemplateTags                   []string          `mapstructure:"template_tags"`
+	TemplateCopyStorageDomains     []string          `mapstructure:"template_copy_storage_domains"`
+	PublishExportDomain            string            `mapstructure:"publish_export_domain"`
+	PublishExportDomainDetach      bool              `mapstructure:"publish_export_domain_detach"`
+
+	TemplateRetention   *TemplateRetentionConfig   `mapstructure:"template_retention"`
+	TemplatePermissions []TemplatePermissionConfig `mapstructure:"template_permissions"`
+	PublishTargets      []PublishTargetConfig      `mapstructure:"publish_target"`
+	OVAExports          []OVAExportConfig          `mapstructure:"ova_export"`
+	OVADownload         *OVADownloadConfig         `mapstructure:"ova_download"`
+
+	// Resolved migration policy ID (not configurable)
+	templateMigrationPolicyID string
 
 	ctx interpolate.Context
 }
@@ -53,6 +116,14 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	err := config.Decode(c, &config.DecodeOpts{
 		Interpolate:        true,
 		InterpolateContext: &c.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			// Tags and OVA exports are interpolated with the build variables at runtime
+			Exclude: []string{
+				"template_tags",
+				"vm_tags",
+				"ova_export",
+			},
+		},
 	}, raws...)
 	if err != nil {
 		return nil, nil, err
@@ -62,6 +133,7 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	var errs *packer.MultiError
 	errs = packer.MultiErrorAppend(errs, c.AccessConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.SourceConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.ShutdownConfig.Prepare(&c.ctx)...)
 
 	if c.VMName == "" {
 		// Default to packer-[time-ordered-uuid]
@@ -91,15 +163,126 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid vm_storage_driver: %s. Must be one of: %v", c.VMStorageDriver, validStorageDrivers))
 	}
 
+	// Validate vm_type value if specified
+	if c.VMType != "" {
+		validVMTypes := []string{"server", "desktop", "high_performance"}
+		validVMType := false
+		for _, vmType := range validVMTypes {
+			if c.VMType == vmType {
+				validVMType = true
+				break
+			}
+		}
+		if !validVMType {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid vm_type: %s. Must be one of: %v", c.VMType, validVMTypes))
+		}
+	}
+
+	// Validate boot_devices values if specified
+	validBootDevices := []string{"hd", "network", "cdrom"}
+	for _, device := range c.BootDevices {
+		validDevice := false
+		for _, bootDevice := range validBootDevices {
+			if device == bootDevice {
+				validDevice = true
+				break
+			}
+		}
+		if !validDevice {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid boot_devices entry: %s. Must be one of: %v", device, validBootDevices))
+		}
+	}
+
+	if c.IOThreads < 0 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("io_threads must not be negative, got %d", c.IOThreads))
+	}
+
+	// Validate disk_cache_mode value if specified
+	if c.DiskCacheMode != "" {
+		validCacheModes := []string{"none", "writethrough", "writeback"}
+		validCacheMode := false
+		for _, mode := range validCacheModes {
+			if c.DiskCacheMode == mode {
+				validCacheMode = true
+				break
+			}
+		}
+		if !validCacheMode {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid disk_cache_mode: %s. Must be one of: %v", c.DiskCacheMode, validCacheModes))
+		}
+		if _, ok := c.CustomProperties[diskCacheCustomProperty]; ok {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Conflict: Set either disk_cache_mode or the %s custom property", diskCacheCustomProperty))
+		}
+	}
+
 	// Validate export configuration
 	if (c.ExportDirectory != "" || c.ExportFileName != "") && c.ExportHost == "" {
 		errs = packer.MultiErrorAppend(errs, errors.New("export_host must be specified when export_directory or export_file_name are set"))
 	}
 
-	// Set default export directory if export_host is specified
-	if c.ExportHost != "" && c.ExportDirectory == "" {
-		c.ExportDirectory = "/tmp"
-		log.Printf("Using default export_directory: %s", c.ExportDirectory)
+	if c.ExportStorageDomainOverwrite && c.ExportStorageDomain == "" {
+		errs = packer.MultiErrorAppend(errs, errors.New("export_storage_domain must be specified when export_storage_domain_overwrite is set"))
+	}
+	if c.ExportStorageDomainDetach && c.ExportStorageDomain == "" {
+		errs = packer.MultiErrorAppend(errs, errors.New("export_storage_domain must be specified when export_storage_domain_detach is set"))
+	}
+
+	// export_host is shorthand for a single ova_export block
+	if c.ExportHost != "" {
+		if len(c.OVAExports) > 0 {
+			errs = packer.MultiErrorAppend(errs, errors.New("Conflict: Set either export_host or ova_export"))
+		}
+		c.OVAExports = []OVAExportConfig{{
+			Host:      c.ExportHost,
+			Directory: c.ExportDirectory,
+			FileName:  c.ExportFileName,
+		}}
+	}
+	for i := range c.OVAExports {
+		errs = packer.MultiErrorAppend(errs, c.OVAExports[i].Prepare(&c.ctx)...)
+	}
+	if c.OVADownload != nil {
+		if len(c.OVAExports) == 0 {
+			errs = packer.MultiErrorAppend(errs, errors.New("export_host or ova_export must be specified when ova_download is set"))
+		}
+		errs = packer.MultiErrorAppend(errs, c.OVADownload.Prepare(&c.ctx)...)
+		if c.OVADownload.OutputDirectory == "" {
+			c.OVADownload.OutputDirectory = fmt.Sprintf("output-%s", c.PackerBuildName)
+			log.Printf("Using default ova_download output_directory: %s", c.OVADownload.OutputDirectory)
+		}
+	}
+
+	// Validate host placement configuration
+	if c.Host != "" && len(c.Hosts) > 0 {
+		errs = packer.MultiErrorAppend(errs, errors.New("Conflict: Set either host or hosts"))
+	}
+	if c.PlaceOnExportHost {
+		if c.Host != "" || len(c.Hosts) > 0 {
+			errs = packer.MultiErrorAppend(errs, errors.New("Conflict: Set either place_on_export_host or host/hosts"))
+		}
+		if len(c.OVAExports) == 0 {
+			errs = packer.MultiErrorAppend(errs, errors.New("export_host or ova_export must be specified when place_on_export_host is set"))
+		} else if strings.Contains(c.OVAExports[0].Host, "{{") {
+			// ova_export is interpolated at runtime, after placement is decided
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("place_on_export_host cannot be used with a templated ova_export host: %s", c.OVAExports[0].Host))
+		} else {
+			c.Hosts = []string{c.OVAExports[0].Host}
+		}
+	} else if c.Host != "" {
+		c.Hosts = []string{c.Host}
+	}
+	if c.PlacementAffinity != "" {
+		validAffinities := []string{"migratable", "user_migratable", "pinned"}
+		validAffinity := false
+		for _, affinity := range validAffinities {
+			if c.PlacementAffinity == affinity {
+				validAffinity = true
+				break
+			}
+		}
+		if !validAffinity {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid placement_affinity: %s. Must be one of: %v", c.PlacementAffinity, validAffinities))
+		}
 	}
 
 	// Set default value for os_interface_name if not specified
@@ -108,14 +291,18 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		log.Printf("Using default os_interface_name: %s", c.OSInterfaceName)
 	}
 
-	// Set default values for VM resources if not specified
-	if c.VmVcpuCount == 0 {
-		c.VmVcpuCount = 1
-		log.Printf("Using default vm_vcpu_count: %d", c.VmVcpuCount)
-	}
-	if c.VmMemoryMB == 0 {
-		c.VmMemoryMB = 1024
-		log.Printf("Using default vm_memory_mb: %d", c.VmMemoryMB)
+	// Set default values for VM resources if not specified. When instance_type
+	// is set, unset resources are taken from the instance type instead, and a
+	// source VM, snapshot, OVA or hardware template keeps its own resources.
+	if c.InstanceType == "" && (c.GetSourceType() == "template" || (c.GetSourceType() == "disk" && c.HardwareTemplateName == "")) {
+		if c.VmVcpuCount == 0 {
+			c.VmVcpuCount = 1
+			log.Printf("Using default vm_vcpu_count: %d", c.VmVcpuCount)
+		}
+		if c.VmMemoryMB == 0 {
+			c.VmMemoryMB = 1024
+			log.Printf("Using default vm_memory_mb: %d", c.VmMemoryMB)
+		}
 	}
 
 	// Set default value for network_name if not specified
@@ -124,6 +311,28 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		log.Printf("Using default network_name: %s", c.NetworkName)
 	}
 
+	// Set default guest agent timeout if waiting for the guest agent
+	if c.WaitForGuestAgent && c.GuestAgentTimeout == 0 {
+		c.GuestAgentTimeout = 10 * time.Minute
+		log.Printf("Using default guest_agent_timeout: %s", c.GuestAgentTimeout)
+	}
+
+	// Set default generalize operations and validate configured ones
+	if !c.Generalize && len(c.GeneralizeOperations) > 0 {
+		errs = packer.MultiErrorAppend(errs, errors.New("generalize must be enabled when generalize_operations is set"))
+	}
+	if c.Generalize && len(c.GeneralizeOperations) == 0 {
+		for _, op := range generalizeOperations {
+			c.GeneralizeOperations = append(c.GeneralizeOperations, op.Name)
+		}
+		log.Printf("Using default generalize_operations: %v", c.GeneralizeOperations)
+	}
+	for _, operation := range c.GeneralizeOperations {
+		if generalizeCommand(operation) == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid generalize_operations entry: %s", operation))
+		}
+	}
+
 	// Set default values for retry configuration
 	if c.MaxRetries == 0 {
 		c.MaxRetries = 4
@@ -133,6 +342,10 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		c.RetryIntervalSec = 2
 		log.Printf("Using default retry_interval_sec: %d", c.RetryIntervalSec)
 	}
+	if c.JobTimeout == 0 {
+		c.JobTimeout = 2 * time.Hour
+		log.Printf("Using default job_timeout: %s", c.JobTimeout)
+	}
 
 	// Set default value for template_seal if not specified
 	if c.TemplateSeal == nil {
@@ -143,6 +356,87 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		log.Printf("Using configured template_seal: %t", *c.TemplateSeal)
 	}
 
+	// Template sub-versions share the name of their base template
+	if c.BaseTemplateName != "" {
+		if c.DestinationTemplateName == "" {
+			c.DestinationTemplateName = c.BaseTemplateName
+			log.Printf("Using base_template_name as destination_template_name: %s", c.DestinationTemplateName)
+		} else if c.DestinationTemplateName != c.BaseTemplateName {
+			errs = packer.MultiErrorAppend(errs, errors.New("destination_template_name must match base_template_name when adding a template sub-version"))
+		}
+		if c.OnTemplateExists != "" {
+			errs = packer.MultiErrorAppend(errs, errors.New("Conflict: Set either base_template_name or on_template_exists"))
+		}
+	} else {
+		// Set default on_template_exists policy, honouring -force
+		if c.OnTemplateExists == "" {
+			c.OnTemplateExists = "fail"
+			if c.PackerForce {
+				c.OnTemplateExists = "replace"
+			}
+			log.Printf("Using default on_template_exists: %s", c.OnTemplateExists)
+		}
+		validPolicies := []string{"fail", "replace", "rename", "version"}
+		validPolicy := false
+		for _, policy := range validPolicies {
+			if c.OnTemplateExists == policy {
+				validPolicy = true
+				break
+			}
+		}
+		if !validPolicy {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid on_template_exists: %s. Must be one of: %v", c.OnTemplateExists, validPolicies))
+		}
+		if c.TemplateVersionName != "" && c.OnTemplateExists != "version" {
+			errs = packer.MultiErrorAppend(errs, errors.New("base_template_name or on_template_exists = \"version\" must be specified when template_version_name is set"))
+		}
+	}
+
+	// Validate template runtime policy
+	if c.TemplateHAPriority < 0 || c.TemplateHAPriority > 100 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("template_ha_priority must be between 0 and 100, got %d", c.TemplateHAPriority))
+	}
+	if !c.TemplateHighAvailability && (c.TemplateHAPriority != 0 || c.TemplateLeaseStorageDomain != "") {
+		errs = packer.MultiErrorAppend(errs, errors.New("template_high_availability must be enabled when template_ha_priority or template_lease_storage_domain are set"))
+	}
+	if c.TemplateMigrationDowntimeMs < 0 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("template_migration_downtime_ms must not be negative, got %d", c.TemplateMigrationDowntimeMs))
+	}
+	if c.TemplateMigrationPolicy != "" {
+		if policyID, ok := migrationPolicyIDs[c.TemplateMigrationPolicy]; ok {
+			c.templateMigrationPolicyID = policyID
+		} else if _, err := googleuuid.Parse(c.TemplateMigrationPolicy); err == nil {
+			c.templateMigrationPolicyID = c.TemplateMigrationPolicy
+		} else {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid template_migration_policy: %s. Must be a migration policy ID or one of: legacy, minimal_downtime, suspend_workload, post_copy", c.TemplateMigrationPolicy))
+		}
+	}
+
+	if c.TemplateRetention != nil {
+		errs = packer.MultiErrorAppend(errs, c.TemplateRetention.Prepare(&c.ctx)...)
+	}
+	for i := range c.TemplatePermissions {
+		errs = packer.MultiErrorAppend(errs, c.TemplatePermissions[i].Prepare(&c.ctx)...)
+	}
+
+	// Validate template publish configuration
+	if len(c.PublishTargets) > 0 && c.PublishExportDomain == "" {
+		errs = packer.MultiErrorAppend(errs, errors.New("publish_export_domain must be specified when publish_target is set"))
+	}
+	if c.PublishExportDomain != "" && len(c.PublishTargets) == 0 {
+		errs = packer.MultiErrorAppend(errs, errors.New("publish_target must be specified when publish_export_domain is set"))
+	}
+	if c.PublishExportDomainDetach && c.PublishExportDomain == "" {
+		errs = packer.MultiErrorAppend(errs, errors.New("publish_export_domain must be specified when publish_export_domain_detach is set"))
+	}
+	if len(c.PublishTargets) > 0 && c.OnTemplateExists == "version" {
+		// Imported templates are always new base templates
+		errs = packer.MultiErrorAppend(errs, errors.New("on_template_exists = \"version\" cannot be used with publish_target"))
+	}
+	for i := range c.PublishTargets {
+		errs = packer.MultiErrorAppend(errs, c.PublishTargets[i].Prepare(&c.ctx)...)
+	}
+
 	errs = packer.MultiErrorAppend(errs, c.Comm.Prepare(&c.ctx)...)
 
 	// Handle SSH timeout after communicator preparation to prevent override
@@ -165,5 +459,8 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	}
 
 	packer.LogSecretFilter.Set(c.Password)
+	if c.OVADownload != nil && c.OVADownload.Password != "" {
+		packer.LogSecretFilter.Set(c.OVADownload.Password)
+	}
 	return c, nil, nil
 }
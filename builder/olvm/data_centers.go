@@ -0,0 +1,119 @@
+package olvm
+
+import (
+	"fmt"
+	"log"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// findClusterID returns the ID of the named cluster
+func findClusterID(connWrapper *ConnectionWrapper, clusterName string) (string, error) {
+	var cResp *ovirtsdk4.ClustersServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		cResp, err = conn.SystemService().
+			ClustersService().
+			List().
+			Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error getting cluster list: %s", err)
+	}
+
+	if clusters, ok := cResp.Clusters(); ok {
+		for _, cluster := range clusters.Slice() {
+			if name, ok := cluster.Name(); ok {
+				if name == clusterName {
+					clusterID := cluster.MustId()
+					log.Printf("Using cluster id: %s", clusterID)
+					return clusterID, nil
+				}
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find cluster '%s'", clusterName)
+}
+
+// findClusterDataCenterID returns the ID of the data center of the given cluster
+func findClusterDataCenterID(connWrapper *ConnectionWrapper, clusterID string) (string, error) {
+	var clusterResp *ovirtsdk4.ClusterServiceGetResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		clusterResp, err = conn.SystemService().ClustersService().ClusterService(clusterID).Get().Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error getting cluster details: %s", err)
+	}
+
+	dataCenter, ok := clusterResp.MustCluster().DataCenter()
+	if !ok {
+		return "", fmt.Errorf("Could not determine data center for cluster %s", clusterID)
+	}
+	return dataCenter.MustId(), nil
+}
+
+// findDataCenterID returns the ID of the named data center
+func findDataCenterID(connWrapper *ConnectionWrapper, dataCenterName string) (string, error) {
+	var dcsResp *ovirtsdk4.DataCentersServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		dcsResp, err = conn.SystemService().DataCentersService().List().
+			Search(fmt.Sprintf("name=%s", dataCenterName)).
+			Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error searching data centers: %s", err)
+	}
+
+	if dataCenters, ok := dcsResp.DataCenters(); ok {
+		for _, dc := range dataCenters.Slice() {
+			if name, ok := dc.Name(); ok && name == dataCenterName {
+				log.Printf("Using data center: %s (ID: %s)", dataCenterName, dc.MustId())
+				return dc.MustId(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find data center '%s'", dataCenterName)
+}
+
+// findClusterDataCenterNames returns the name of the data center of each
+// cluster, by cluster ID
+func findClusterDataCenterNames(connWrapper *ConnectionWrapper) (map[string]string, error) {
+	var dcsResp *ovirtsdk4.DataCentersServiceListResponse
+	var cResp *ovirtsdk4.ClustersServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		dcsResp, err = conn.SystemService().DataCentersService().List().Send()
+		if err != nil {
+			return err
+		}
+		cResp, err = conn.SystemService().ClustersService().List().Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting data centers and clusters: %s", err)
+	}
+
+	dataCenterNames := make(map[string]string)
+	if dataCenters, ok := dcsResp.DataCenters(); ok {
+		for _, dc := range dataCenters.Slice() {
+			dataCenterNames[dc.MustId()] = dc.MustName()
+		}
+	}
+
+	clusterDataCenterNames := make(map[string]string)
+	if clusters, ok := cResp.Clusters(); ok {
+		for _, cluster := range clusters.Slice() {
+			if dc, ok := cluster.DataCenter(); ok {
+				clusterDataCenterNames[cluster.MustId()] = dataCenterNames[dc.MustId()]
+			}
+		}
+	}
+	return clusterDataCenterNames, nil
+}
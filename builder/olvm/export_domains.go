@@ -0,0 +1,216 @@
+package olvm
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// attachExportDomain makes sure the export storage domain is attached to, and
+// active in, the given data center. An export domain can only be attached to
+// one data center at a time, so it is detached from any other data center
+// first, provided canDetach allows it for that data center.
+func attachExportDomain(connWrapper *ConnectionWrapper, state multistep.StateBag, storageDomainID, dataCenterID string, canDetach func(dataCenterID string) error) error {
+	var sdResp *ovirtsdk4.StorageDomainServiceGetResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		sdResp, err = conn.SystemService().StorageDomainsService().StorageDomainService(storageDomainID).Get().Send()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Error getting storage domain %s: %s", storageDomainID, err)
+	}
+
+	attached := false
+	if dataCenters, ok := sdResp.MustStorageDomain().DataCenters(); ok {
+		for _, dc := range dataCenters.Slice() {
+			if dc.MustId() == dataCenterID {
+				attached = true
+				continue
+			}
+			if err := canDetach(dc.MustId()); err != nil {
+				return err
+			}
+			if err := detachStorageDomain(connWrapper, state, storageDomainID, dc.MustId()); err != nil {
+				return err
+			}
+		}
+	}
+
+	attachedService := func(conn *ovirtsdk4.Connection) *ovirtsdk4.AttachedStorageDomainService {
+		return conn.SystemService().
+			DataCentersService().
+			DataCenterService(dataCenterID).
+			StorageDomainsService().
+			StorageDomainService(storageDomainID)
+	}
+
+	if !attached {
+		log.Printf("Attaching storage domain %s to data center %s", storageDomainID, dataCenterID)
+		err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			_, err := conn.SystemService().
+				DataCentersService().
+				DataCenterService(dataCenterID).
+				StorageDomainsService().
+				Add().
+				StorageDomain(ovirtsdk4.NewStorageDomainBuilder().Id(storageDomainID).MustBuild()).
+				Send()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Error attaching storage domain %s to data center %s: %s", storageDomainID, dataCenterID, err)
+		}
+	} else {
+		_, status, err := AttachedStorageDomainStateRefreshFuncWithWrapper(connWrapper, dataCenterID, storageDomainID)()
+		if err != nil {
+			return fmt.Errorf("Error getting storage domain %s status: %s", storageDomainID, err)
+		}
+		if status == string(ovirtsdk4.STORAGEDOMAINSTATUS_MAINTENANCE) {
+			log.Printf("Activating storage domain %s in data center %s", storageDomainID, dataCenterID)
+			err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+				_, err := attachedService(conn).Activate().Send()
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("Error activating storage domain %s: %s", storageDomainID, err)
+			}
+		}
+	}
+
+	sdStateChange := StateChangeConf{
+		Pending: []string{
+			"",
+			string(ovirtsdk4.STORAGEDOMAINSTATUS_LOCKED),
+			string(ovirtsdk4.STORAGEDOMAINSTATUS_MAINTENANCE),
+			string(ovirtsdk4.STORAGEDOMAINSTATUS_ACTIVATING),
+			string(ovirtsdk4.STORAGEDOMAINSTATUS_UNKNOWN),
+		},
+		Target:    []string{string(ovirtsdk4.STORAGEDOMAINSTATUS_ACTIVE)},
+		Refresh:   AttachedStorageDomainStateRefreshFuncWithWrapper(connWrapper, dataCenterID, storageDomainID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&sdStateChange); err != nil {
+		return fmt.Errorf("Error waiting for storage domain %s to become active: %s", storageDomainID, err)
+	}
+	return nil
+}
+
+// detachStorageDomain moves the storage domain to maintenance in the given
+// data center and detaches it
+func detachStorageDomain(connWrapper *ConnectionWrapper, state multistep.StateBag, storageDomainID, dataCenterID string) error {
+	attachedService := func(conn *ovirtsdk4.Connection) *ovirtsdk4.AttachedStorageDomainService {
+		return conn.SystemService().
+			DataCentersService().
+			DataCenterService(dataCenterID).
+			StorageDomainsService().
+			StorageDomainService(storageDomainID)
+	}
+
+	_, status, err := AttachedStorageDomainStateRefreshFuncWithWrapper(connWrapper, dataCenterID, storageDomainID)()
+	if err != nil {
+		return fmt.Errorf("Error getting storage domain %s status: %s", storageDomainID, err)
+	}
+	if status != string(ovirtsdk4.STORAGEDOMAINSTATUS_MAINTENANCE) {
+		log.Printf("Moving storage domain %s to maintenance in data center %s", storageDomainID, dataCenterID)
+		err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			_, err := attachedService(conn).Deactivate().Send()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Error moving storage domain %s to maintenance: %s", storageDomainID, err)
+		}
+
+		sdStateChange := StateChangeConf{
+			Pending: []string{
+				string(ovirtsdk4.STORAGEDOMAINSTATUS_ACTIVE),
+				string(ovirtsdk4.STORAGEDOMAINSTATUS_LOCKED),
+				string(ovirtsdk4.STORAGEDOMAINSTATUS_PREPARING_FOR_MAINTENANCE),
+			},
+			Target:    []string{string(ovirtsdk4.STORAGEDOMAINSTATUS_MAINTENANCE)},
+			Refresh:   AttachedStorageDomainStateRefreshFuncWithWrapper(connWrapper, dataCenterID, storageDomainID),
+			StepState: state,
+		}
+		if _, err := WaitForState(&sdStateChange); err != nil {
+			return fmt.Errorf("Error waiting for storage domain %s to enter maintenance: %s", storageDomainID, err)
+		}
+	}
+
+	log.Printf("Detaching storage domain %s from data center %s", storageDomainID, dataCenterID)
+	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		_, err := attachedService(conn).Remove().Send()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Error detaching storage domain %s: %s", storageDomainID, err)
+	}
+
+	sdStateChange := StateChangeConf{
+		Pending:   []string{string(ovirtsdk4.STORAGEDOMAINSTATUS_MAINTENANCE), string(ovirtsdk4.STORAGEDOMAINSTATUS_LOCKED)},
+		Target:    []string{""},
+		Refresh:   AttachedStorageDomainStateRefreshFuncWithWrapper(connWrapper, dataCenterID, storageDomainID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&sdStateChange); err != nil {
+		return fmt.Errorf("Error waiting for storage domain %s to be detached: %s", storageDomainID, err)
+	}
+	return nil
+}
+
+// exportTemplateToDomain exports the template to the export storage domain
+// and waits for the engine export job to finish
+func exportTemplateToDomain(connWrapper *ConnectionWrapper, ui packer.Ui, state multistep.StateBag, templateID, storageDomainID string, exclusive bool) error {
+	// The correlation ID ties the engine job and events to this export
+	correlationID := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	log.Printf("Exporting template %s to storage domain %s (exclusive: %t, correlation ID: %s)", templateID, storageDomainID, exclusive, correlationID)
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		_, err := conn.SystemService().
+			TemplatesService().
+			TemplateService(templateID).
+			Export().
+			Header("Correlation-Id", correlationID).
+			StorageDomain(ovirtsdk4.NewStorageDomainBuilder().Id(storageDomainID).MustBuild()).
+			Exclusive(exclusive).
+			Send()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Error exporting template %s: %s", templateID, err)
+	}
+
+	if err := waitForJob(connWrapper, ui, state, "Export job step", correlationID); err != nil {
+		return fmt.Errorf("Error exporting template %s: %s", templateID, err)
+	}
+	return nil
+}
+
+// findExportedTemplate returns the template with the given ID stored on the
+// export storage domain, or nil if the export domain does not hold it
+func findExportedTemplate(connWrapper *ConnectionWrapper, storageDomainID, templateID string) (*ovirtsdk4.Template, error) {
+	var tpsResp *ovirtsdk4.StorageDomainTemplatesServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		tpsResp, err = conn.SystemService().
+			StorageDomainsService().
+			StorageDomainService(storageDomainID).
+			TemplatesService().
+			List().
+			Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing templates on storage domain %s: %s", storageDomainID, err)
+	}
+
+	if templates, ok := tpsResp.Templates(); ok {
+		for _, tp := range templates.Slice() {
+			if tp.MustId() == templateID {
+				return tp, nil
+			}
+		}
+	}
+	return nil, nil
+}
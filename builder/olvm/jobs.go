@@ -0,0 +1,164 @@
+package olvm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// jobRegistrationTimeout bounds how long the engine may take to report a job
+// after the request that starts it was accepted
+const jobRegistrationTimeout = 5 * time.Minute
+
+// jobRefreshFunc returns a StateRefreshFunc that watches the engine job with
+// the given correlation ID, reporting new or progressing job steps prefixed
+// with the given label. The state is empty until the engine reports the job,
+// which fails once jobRegistrationTimeout has passed.
+func jobRefreshFunc(connWrapper *ConnectionWrapper, ui packer.Ui, label, correlationID string) StateRefreshFunc {
+	reported := make(map[string]string)
+	started := time.Now()
+	return func() (interface{}, string, error) {
+		job, err := findJob(connWrapper, correlationID)
+		if err != nil {
+			return nil, "", err
+		}
+		if job == nil {
+			if time.Since(started) > jobRegistrationTimeout {
+				return nil, "", fmt.Errorf("%w: no job reported for correlation ID %s after %s", errStateChangeTimeout, correlationID, jobRegistrationTimeout)
+			}
+			return nil, "", nil
+		}
+
+		steps, err := jobSteps(connWrapper, job.MustId())
+		if err != nil {
+			return nil, "", err
+		}
+		for _, step := range steps {
+			description, _ := step.Description()
+			progress := ""
+			if p, ok := step.Progress(); ok {
+				progress = fmt.Sprintf(" (%d%%)", p)
+			}
+			line := fmt.Sprintf("%s: %s%s", description, step.MustStatus(), progress)
+			if reported[step.MustId()] != line {
+				reported[step.MustId()] = line
+				ui.Message(fmt.Sprintf("%s: %s", label, line))
+			}
+		}
+
+		return job, string(job.MustStatus()), nil
+	}
+}
+
+// jobFailure describes why the job did not finish, from its failed steps and
+// the error events with the job correlation ID
+func jobFailure(connWrapper *ConnectionWrapper, job *ovirtsdk4.Job, correlationID string) string {
+	var reasons []string
+	if steps, err := jobSteps(connWrapper, job.MustId()); err == nil {
+		for _, step := range steps {
+			if status, _ := step.Status(); status == ovirtsdk4.STEPSTATUS_FAILED || status == ovirtsdk4.STEPSTATUS_ABORTED {
+				reasons = append(reasons, fmt.Sprintf("step '%s' %s", step.MustDescription(), status))
+			}
+		}
+	}
+	if events, err := findEvents(connWrapper, correlationID); err == nil {
+		for _, event := range events {
+			if severity, _ := event.Severity(); severity == ovirtsdk4.LOGSEVERITY_ERROR || severity == ovirtsdk4.LOGSEVERITY_ALERT {
+				reasons = append(reasons, event.MustDescription())
+			}
+		}
+	}
+	if len(reasons) == 0 {
+		return job.MustDescription()
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// waitForJob waits up to job_timeout for the engine job with the given
+// correlation ID to end, failing with the reason unless the job finished
+func waitForJob(connWrapper *ConnectionWrapper, ui packer.Ui, state multistep.StateBag, label, correlationID string) error {
+	config := state.Get("config").(*Config)
+	jobStateChange := StateChangeConf{
+		Pending: []string{"", string(ovirtsdk4.JOBSTATUS_STARTED), string(ovirtsdk4.JOBSTATUS_UNKNOWN)},
+		Target: []string{
+			string(ovirtsdk4.JOBSTATUS_FINISHED),
+			string(ovirtsdk4.JOBSTATUS_FAILED),
+			string(ovirtsdk4.JOBSTATUS_ABORTED),
+		},
+		Refresh:   jobRefreshFunc(connWrapper, ui, label, correlationID),
+		StepState: state,
+		Timeout:   config.JobTimeout,
+	}
+	result, err := WaitForState(&jobStateChange)
+	if err != nil {
+		return err
+	}
+	job := result.(*ovirtsdk4.Job)
+	if status := job.MustStatus(); status != ovirtsdk4.JOBSTATUS_FINISHED {
+		return fmt.Errorf("job %s: %s", status, jobFailure(connWrapper, job, correlationID))
+	}
+	return nil
+}
+
+// findJob returns the engine job with the given correlation ID, or nil if
+// the engine does not report it (yet)
+func findJob(connWrapper *ConnectionWrapper, correlationID string) (*ovirtsdk4.Job, error) {
+	var jobsResp *ovirtsdk4.JobsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		jobsResp, err = conn.SystemService().JobsService().List().
+			Search(fmt.Sprintf("correlation_id=%s", correlationID)).
+			Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error searching jobs: %s", err)
+	}
+
+	if jobs, ok := jobsResp.Jobs(); ok && len(jobs.Slice()) > 0 {
+		return jobs.Slice()[0], nil
+	}
+	return nil, nil
+}
+
+// jobSteps returns the steps of the engine job
+func jobSteps(connWrapper *ConnectionWrapper, jobID string) ([]*ovirtsdk4.Step, error) {
+	var stepsResp *ovirtsdk4.StepsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		stepsResp, err = conn.SystemService().JobsService().JobService(jobID).StepsService().List().Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting steps of job %s: %s", jobID, err)
+	}
+
+	if steps, ok := stepsResp.Steps(); ok {
+		return steps.Slice(), nil
+	}
+	return nil, nil
+}
+
+// findEvents returns the engine events with the given correlation ID
+func findEvents(connWrapper *ConnectionWrapper, correlationID string) ([]*ovirtsdk4.Event, error) {
+	var eventsResp *ovirtsdk4.EventsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		eventsResp, err = conn.SystemService().EventsService().List().
+			Search(fmt.Sprintf("correlation_id=%s", correlationID)).
+			Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error searching events: %s", err)
+	}
+
+	if events, ok := eventsResp.Events(); ok {
+		return events.Slice(), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,57 @@
+package olvm
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// OVADownloadConfig contains the SSH settings used to download the exported
+// OVA files from their hosts to the build machine
+type OVADownloadConfig struct {
+	Username              string `mapstructure:"username"`
+	Password              string `mapstructure:"password"`
+	PrivateKeyFile        string `mapstructure:"private_key_file"`
+	Port                  int    `mapstructure:"port"`
+	KnownHostsFile        string `mapstructure:"known_hosts_file"`
+	InsecureIgnoreHostKey bool   `mapstructure:"insecure_ignore_host_key"`
+	OutputDirectory       string `mapstructure:"output_directory"`
+}
+
+// Prepare performs basic validation on the OVADownloadConfig
+func (c *OVADownloadConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if c.Username == "" {
+		c.Username = "root"
+		log.Printf("Using default ova_download username: %s", c.Username)
+	}
+	if c.Port == 0 {
+		c.Port = 22
+		log.Printf("Using default ova_download port: %d", c.Port)
+	}
+	if c.Password == "" && c.PrivateKeyFile == "" {
+		errs = append(errs, errors.New("ova_download requires password or private_key_file"))
+	}
+	if c.PrivateKeyFile != "" {
+		if _, err := os.Stat(c.PrivateKeyFile); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid ova_download private_key_file: %s", err))
+		}
+	}
+	if c.KnownHostsFile != "" {
+		if _, err := os.Stat(c.KnownHostsFile); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid ova_download known_hosts_file: %s", err))
+		}
+	}
+	if c.KnownHostsFile == "" && !c.InsecureIgnoreHostKey {
+		errs = append(errs, errors.New("ova_download requires known_hosts_file or insecure_ignore_host_key"))
+	}
+	if c.KnownHostsFile != "" && c.InsecureIgnoreHostKey {
+		errs = append(errs, errors.New("Conflict: Set either known_hosts_file or insecure_ignore_host_key in ova_download"))
+	}
+
+	return errs
+}
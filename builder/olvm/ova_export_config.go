@@ -0,0 +1,34 @@
+package olvm
+
+import (
+	"errors"
+	"log"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// OVAExportConfig is a host and path the template is exported to as an OVA
+type OVAExportConfig struct {
+	Host      string `mapstructure:"host"`
+	Directory string `mapstructure:"directory"`
+	FileName  string `mapstructure:"file_name"`
+}
+
+// Prepare performs basic validation on the OVAExportConfig
+func (c *OVAExportConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if c.Host == "" {
+		errs = append(errs, errors.New("ova_export requires host"))
+	}
+	if c.Directory == "" {
+		c.Directory = "/tmp"
+		log.Printf("Using default ova_export directory: %s", c.Directory)
+	}
+	if c.FileName == "" {
+		c.FileName = "{{ .TemplateName }}.ova"
+		log.Printf("Using default ova_export file_name: %s", c.FileName)
+	}
+
+	return errs
+}
@@ -0,0 +1,166 @@
+package olvm
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// templatePermission is a template permission with its principal and role
+// resolved to engine IDs
+type templatePermission struct {
+	Principal string
+	Role      string
+	UserID    string
+	GroupID   string
+	RoleID    string
+}
+
+// resolveTemplatePermissions looks up the users, groups and roles of the
+// configured template permissions
+func resolveTemplatePermissions(connWrapper *ConnectionWrapper, permissions []TemplatePermissionConfig) ([]templatePermission, error) {
+	var rolesResp *ovirtsdk4.RolesServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		rolesResp, err = conn.SystemService().RolesService().List().Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting roles: %s", err)
+	}
+	roleIDs := make(map[string]string)
+	if roles, ok := rolesResp.Roles(); ok {
+		for _, role := range roles.Slice() {
+			if name, ok := role.Name(); ok {
+				roleIDs[name] = role.MustId()
+			}
+		}
+	}
+
+	var resolved []templatePermission
+	for _, permission := range permissions {
+		roleID, ok := roleIDs[permission.Role]
+		if !ok {
+			return nil, fmt.Errorf("Could not find role '%s'", permission.Role)
+		}
+
+		p := templatePermission{
+			Principal: permission.principal(),
+			Role:      permission.Role,
+			RoleID:    roleID,
+		}
+		if permission.User != "" {
+			p.UserID, err = findUserID(connWrapper, permission.User)
+		} else {
+			p.GroupID, err = findGroupID(connWrapper, permission.Group)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("Resolved template permission: %s (role %s, ID: %s)", p.Principal, p.Role, p.RoleID)
+		resolved = append(resolved, p)
+	}
+
+	return resolved, nil
+}
+
+// findUserID returns the ID of the user with the given user name. The
+// authorization domain suffix (e.g. "@internal-authz") may be omitted if the
+// name is unambiguous.
+func findUserID(connWrapper *ConnectionWrapper, userName string) (string, error) {
+	var usersResp *ovirtsdk4.UsersServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		usersResp, err = conn.SystemService().UsersService().List().
+			Search(fmt.Sprintf("usrname=%s*", strings.SplitN(userName, "@", 2)[0])).
+			Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error searching users: %s", err)
+	}
+
+	var matches []*ovirtsdk4.User
+	if users, ok := usersResp.Users(); ok {
+		for _, user := range users.Slice() {
+			name, _ := user.UserName()
+			if name == userName || strings.HasPrefix(name, userName+"@") {
+				matches = append(matches, user)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("Could not find user '%s'", userName)
+	case 1:
+		return matches[0].MustId(), nil
+	default:
+		var names []string
+		for _, user := range matches {
+			names = append(names, user.MustUserName())
+		}
+		return "", fmt.Errorf("User name '%s' is ambiguous, specify one of: %s", userName, strings.Join(names, ", "))
+	}
+}
+
+// findGroupID returns the ID of the group with the given name
+func findGroupID(connWrapper *ConnectionWrapper, groupName string) (string, error) {
+	var groupsResp *ovirtsdk4.GroupsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		groupsResp, err = conn.SystemService().GroupsService().List().
+			Search(fmt.Sprintf("name=%s", groupName)).
+			Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error searching groups: %s", err)
+	}
+
+	if groups, ok := groupsResp.Groups(); ok {
+		for _, group := range groups.Slice() {
+			if name, ok := group.Name(); ok && name == groupName {
+				return group.MustId(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find group '%s'", groupName)
+}
+
+// assignTemplatePermissions grants the resolved permissions on the template
+func assignTemplatePermissions(connWrapper *ConnectionWrapper, templateID string, permissions []templatePermission) error {
+	for _, p := range permissions {
+		permissionBuilder := ovirtsdk4.NewPermissionBuilder().
+			Role(ovirtsdk4.NewRoleBuilder().Id(p.RoleID).MustBuild())
+		if p.UserID != "" {
+			permissionBuilder.User(ovirtsdk4.NewUserBuilder().Id(p.UserID).MustBuild())
+		} else {
+			permissionBuilder.Group(ovirtsdk4.NewGroupBuilder().Id(p.GroupID).MustBuild())
+		}
+		permission, err := permissionBuilder.Build()
+		if err != nil {
+			return fmt.Errorf("Error creating permission object: %s", err)
+		}
+
+		log.Printf("Granting role %s on template %s to %s", p.Role, templateID, p.Principal)
+		err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			_, err := conn.SystemService().
+				TemplatesService().
+				TemplateService(templateID).
+				PermissionsService().
+				Add().
+				Permission(permission).
+				Send()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Error granting role %s to %s: %s", p.Role, p.Principal, err)
+		}
+	}
+	return nil
+}
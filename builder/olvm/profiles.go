@@ -0,0 +1,138 @@
+package olvm
+
+import (
+	"fmt"
+	"log"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// findQuotaID returns the ID of the named quota in the data center of the given cluster
+func findQuotaID(connWrapper *ConnectionWrapper, clusterID, quotaName string) (string, error) {
+	dataCenterID, err := findClusterDataCenterID(connWrapper, clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	var quotasResp *ovirtsdk4.QuotasServiceListResponse
+	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		quotasResp, err = conn.SystemService().
+			DataCentersService().
+			DataCenterService(dataCenterID).
+			QuotasService().
+			List().
+			Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error getting quotas: %s", err)
+	}
+
+	if quotas, ok := quotasResp.Quotas(); ok {
+		for _, quota := range quotas.Slice() {
+			if name, ok := quota.Name(); ok && name == quotaName {
+				log.Printf("Using quota: %s (ID: %s)", quotaName, quota.MustId())
+				return quota.MustId(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find quota '%s' in data center %s", quotaName, dataCenterID)
+}
+
+// findCPUProfileID returns the ID of the named CPU profile assigned to the given cluster
+func findCPUProfileID(connWrapper *ConnectionWrapper, clusterID, profileName string) (string, error) {
+	var profilesResp *ovirtsdk4.AssignedCpuProfilesServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		profilesResp, err = conn.SystemService().
+			ClustersService().
+			ClusterService(clusterID).
+			CpuProfilesService().
+			List().
+			Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error getting CPU profiles: %s", err)
+	}
+
+	if profiles, ok := profilesResp.Profiles(); ok {
+		for _, profile := range profiles.Slice() {
+			if name, ok := profile.Name(); ok && name == profileName {
+				log.Printf("Using CPU profile: %s (ID: %s)", profileName, profile.MustId())
+				return profile.MustId(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find CPU profile '%s' in cluster %s", profileName, clusterID)
+}
+
+// findDiskProfileID returns the ID of the named disk profile assigned to the given storage domain
+func findDiskProfileID(connWrapper *ConnectionWrapper, storageDomainID, profileName string) (string, error) {
+	var profilesResp *ovirtsdk4.AssignedDiskProfilesServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		profilesResp, err = conn.SystemService().
+			StorageDomainsService().
+			StorageDomainService(storageDomainID).
+			DiskProfilesService().
+			List().
+			Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error getting disk profiles: %s", err)
+	}
+
+	if profiles, ok := profilesResp.Profiles(); ok {
+		for _, profile := range profiles.Slice() {
+			if name, ok := profile.Name(); ok && name == profileName {
+				log.Printf("Using disk profile: %s (ID: %s)", profileName, profile.MustId())
+				return profile.MustId(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find disk profile '%s' on storage domain %s", profileName, storageDomainID)
+}
+
+// buildProfiledDisk returns a disk reference carrying the configured quota and
+// disk profile, resolved against the storage domain of the given disk
+func buildProfiledDisk(connWrapper *ConnectionWrapper, config *Config, diskID, quotaID string) (*ovirtsdk4.Disk, error) {
+	diskBuilder := ovirtsdk4.NewDiskBuilder().Id(diskID)
+
+	if quotaID != "" {
+		diskBuilder.Quota(ovirtsdk4.NewQuotaBuilder().Id(quotaID).MustBuild())
+	}
+
+	if config.DiskProfile != "" {
+		var diskResp *ovirtsdk4.DiskServiceGetResponse
+		err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			var err error
+			diskResp, err = conn.SystemService().DisksService().DiskService(diskID).Get().Send()
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error getting disk %s: %s", diskID, err)
+		}
+
+		storageDomains, ok := diskResp.MustDisk().StorageDomains()
+		if !ok || len(storageDomains.Slice()) == 0 {
+			return nil, fmt.Errorf("Could not determine storage domain for disk %s", diskID)
+		}
+		storageDomainID := storageDomains.Slice()[0].MustId()
+
+		diskProfileID, err := findDiskProfileID(connWrapper, storageDomainID, config.DiskProfile)
+		if err != nil {
+			return nil, err
+		}
+		diskBuilder.
+			DiskProfile(ovirtsdk4.NewDiskProfileBuilder().Id(diskProfileID).MustBuild()).
+			StorageDomainsOfAny(ovirtsdk4.NewStorageDomainBuilder().Id(storageDomainID).MustBuild())
+	}
+
+	return diskBuilder.Build()
+}
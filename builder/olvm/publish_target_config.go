@@ -0,0 +1,31 @@
+package olvm
+
+import (
+	"errors"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// PublishTargetConfig is a data center the template is published to
+type PublishTargetConfig struct {
+	DataCenter    string `mapstructure:"data_center"`
+	Cluster       string `mapstructure:"cluster"`
+	StorageDomain string `mapstructure:"storage_domain"`
+}
+
+// Prepare performs basic validation on the PublishTargetConfig
+func (c *PublishTargetConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if c.DataCenter == "" {
+		errs = append(errs, errors.New("publish_target requires data_center"))
+	}
+	if c.Cluster == "" {
+		errs = append(errs, errors.New("publish_target requires cluster"))
+	}
+	if c.StorageDomain == "" {
+		errs = append(errs, errors.New("publish_target requires storage_domain"))
+	}
+
+	return errs
+}
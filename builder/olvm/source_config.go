@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
@@ -14,14 +15,27 @@ type SourceConfig struct {
 	Cluster string `mapstructure:"cluster"`
 
 	SourceTemplateName    string `mapstructure:"source_template_name"`
-	SourceTemplateVersion int    `mapstructure:"source_template_version"`
+	SourceTemplateVersion string `mapstructure:"source_template_version"`
 	SourceTemplateID      string `mapstructure:"source_template_id"`
+	DataCenter            string `mapstructure:"data_center"`
 
 	SourceDiskName string `mapstructure:"source_disk_name"`
 	SourceDiskID   string `mapstructure:"source_disk_id"`
 
+	HardwareTemplateName string `mapstructure:"hardware_template_name"`
+
+	SourceVMName     string `mapstructure:"source_vm_name"`
+	SourceVMID       string `mapstructure:"source_vm_id"`
+	SourceVMSnapshot string `mapstructure:"source_vm_snapshot"`
+
+	SourceSnapshot *SourceSnapshotConfig `mapstructure:"source_snapshot"`
+	SourceOVA      *SourceOVAConfig      `mapstructure:"source_ova"`
+
 	// Derived source type (not configurable)
 	sourceType string
+
+	// Parsed source_template_version, 0 for the latest version (not configurable)
+	sourceTemplateVersionNumber int
 }
 
 // Prepare performs basic validation on the SourceConfig
@@ -38,15 +52,34 @@ func (c *SourceConfig) Prepare(ctx *interpolate.Context) []error {
 	// Check for conflicting parameters
 	hasTemplate := (c.SourceTemplateName != "") || (c.SourceTemplateID != "")
 	hasDisk := (c.SourceDiskName != "") || (c.SourceDiskID != "")
-	if hasTemplate && hasDisk {
-		errs = append(errs, errors.New("Cannot specify both template and disk source parameters. Use either source_template_name/id or source_disk_name/id"))
+	hasVM := (c.SourceVMName != "") || (c.SourceVMID != "")
+	hasSnapshot := c.SourceSnapshot != nil
+	hasOVA := c.SourceOVA != nil
+	sourceCount := 0
+	for _, has := range []bool{hasTemplate, hasDisk, hasVM, hasSnapshot, hasOVA} {
+		if has {
+			sourceCount++
+		}
+	}
+	if sourceCount > 1 {
+		errs = append(errs, errors.New("Cannot specify more than one kind of source parameters. Use either source_template_name/id, source_disk_name/id, source_vm_name/id, source_snapshot or source_ova"))
 	}
 
 	// Validate template parameters if template source
 	if c.sourceType == "template" {
-		if (c.SourceTemplateName != "") && (c.SourceTemplateVersion < 1) {
-			c.SourceTemplateVersion = 1
-			log.Printf("Using default source_template_version: %d", c.SourceTemplateVersion)
+		if (c.SourceTemplateName != "") && (c.SourceTemplateVersion == "") {
+			c.SourceTemplateVersion = "1"
+			log.Printf("Using default source_template_version: %s", c.SourceTemplateVersion)
+		}
+		if c.SourceTemplateVersion != "" && c.SourceTemplateVersion != "latest" {
+			versionNumber, err := strconv.Atoi(c.SourceTemplateVersion)
+			if err != nil || versionNumber < 1 {
+				errs = append(errs, fmt.Errorf("Invalid source_template_version: %s, must be a positive number or \"latest\"", c.SourceTemplateVersion))
+			}
+			c.sourceTemplateVersionNumber = versionNumber
+		}
+		if (c.SourceTemplateID != "") && ((c.SourceTemplateVersion != "") || (c.DataCenter != "")) {
+			errs = append(errs, errors.New("source_template_version and data_center can only be used with source_template_name"))
 		}
 		if c.SourceTemplateID != "" {
 			if _, err := uuid.Parse(c.SourceTemplateID); err != nil {
@@ -70,9 +103,42 @@ func (c *SourceConfig) Prepare(ctx *interpolate.Context) []error {
 		}
 	}
 
+	if (c.DataCenter != "") && (c.SourceTemplateName == "") && (c.HardwareTemplateName == "") {
+		errs = append(errs, errors.New("data_center requires source_template_name or hardware_template_name"))
+	}
+
+	if (c.HardwareTemplateName != "") && (c.sourceType != "disk") {
+		errs = append(errs, errors.New("hardware_template_name requires source_disk_name or source_disk_id"))
+	}
+
+	// Validate VM parameters if VM source
+	if c.sourceType == "vm" {
+		if c.SourceVMID != "" {
+			if _, err := uuid.Parse(c.SourceVMID); err != nil {
+				errs = append(errs, fmt.Errorf("Invalid source_vm_id: %s", c.SourceVMID))
+			}
+		}
+		if (c.SourceVMName != "") && (c.SourceVMID != "") {
+			errs = append(errs, errors.New("Conflict: Set either source_vm_name or source_vm_id"))
+		}
+	}
+	// Validate snapshot parameters if snapshot source
+	if c.sourceType == "snapshot" {
+		errs = append(errs, c.SourceSnapshot.Prepare(ctx)...)
+	}
+
+	// Validate OVA parameters if OVA source
+	if c.sourceType == "ova" {
+		errs = append(errs, c.SourceOVA.Prepare(ctx)...)
+	}
+
+	if (c.SourceVMSnapshot != "") && !hasVM {
+		errs = append(errs, errors.New("source_vm_snapshot requires source_vm_name or source_vm_id"))
+	}
+
 	// Check if no source parameters are provided at all
-	if !hasTemplate && !hasDisk {
-		errs = append(errs, errors.New("Either source_template_name/id or source_disk_name/id must be specified"))
+	if sourceCount == 0 {
+		errs = append(errs, errors.New("Either source_template_name/id, source_disk_name/id, source_vm_name/id, source_snapshot or source_ova must be specified"))
 	}
 
 	if len(errs) > 0 {
@@ -86,8 +152,11 @@ func (c *SourceConfig) Prepare(ctx *interpolate.Context) []error {
 func (c *SourceConfig) deriveSourceType() string {
 	hasTemplate := (c.SourceTemplateName != "") || (c.SourceTemplateID != "")
 	hasDisk := (c.SourceDiskName != "") || (c.SourceDiskID != "")
+	hasVM := (c.SourceVMName != "") || (c.SourceVMID != "")
+	hasSnapshot := c.SourceSnapshot != nil
+	hasOVA := c.SourceOVA != nil
 
-	if hasTemplate && hasDisk {
+	if hasTemplate && (hasDisk || hasVM || hasSnapshot || hasOVA) {
 		// This will be caught by validation, but we need to return something
 		return "template" // default fallback
 	}
@@ -100,6 +169,18 @@ func (c *SourceConfig) deriveSourceType() string {
 		return "disk"
 	}
 
+	if hasVM {
+		return "vm"
+	}
+
+	if hasSnapshot {
+		return "snapshot"
+	}
+
+	if hasOVA {
+		return "ova"
+	}
+
 	// Default to template if no parameters provided
 	return "template"
 }
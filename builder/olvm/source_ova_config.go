@@ -0,0 +1,42 @@
+package olvm
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// SourceOVAConfig is an OVA file on a host which is imported as the build VM
+type SourceOVAConfig struct {
+	Host          string `mapstructure:"host"`
+	Path          string `mapstructure:"path"`
+	StorageDomain string `mapstructure:"storage_domain"`
+	Sparse        bool   `mapstructure:"sparse"`
+}
+
+// Prepare performs basic validation on the SourceOVAConfig
+func (c *SourceOVAConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if c.Host == "" {
+		errs = append(errs, errors.New("source_ova requires host"))
+	}
+	if c.Path == "" {
+		errs = append(errs, errors.New("source_ova requires path"))
+	} else if !path.IsAbs(c.Path) {
+		errs = append(errs, fmt.Errorf("Invalid source_ova path, must be absolute: %s", c.Path))
+	}
+	if c.StorageDomain == "" {
+		errs = append(errs, errors.New("source_ova requires storage_domain"))
+	}
+
+	return errs
+}
+
+// baseName returns the OVA file name without its extension
+func (c *SourceOVAConfig) baseName() string {
+	return strings.TrimSuffix(path.Base(c.Path), path.Ext(c.Path))
+}
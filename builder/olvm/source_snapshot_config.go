@@ -0,0 +1,56 @@
+package olvm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// SourceSnapshotConfig is a VM snapshot the build VM is cloned from
+type SourceSnapshotConfig struct {
+	VMName      string `mapstructure:"vm_name"`
+	VMID        string `mapstructure:"vm_id"`
+	Description string `mapstructure:"description"`
+	ID          string `mapstructure:"id"`
+}
+
+// Prepare performs basic validation on the SourceSnapshotConfig
+func (c *SourceSnapshotConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if (c.VMName == "") && (c.VMID == "") {
+		errs = append(errs, errors.New("source_snapshot requires vm_name or vm_id"))
+	}
+	if (c.VMName != "") && (c.VMID != "") {
+		errs = append(errs, errors.New("Conflict: Set either vm_name or vm_id in source_snapshot"))
+	}
+	if c.VMID != "" {
+		if _, err := uuid.Parse(c.VMID); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid source_snapshot vm_id: %s", c.VMID))
+		}
+	}
+
+	if (c.Description == "") && (c.ID == "") {
+		errs = append(errs, errors.New("source_snapshot requires description or id"))
+	}
+	if (c.Description != "") && (c.ID != "") {
+		errs = append(errs, errors.New("Conflict: Set either description or id in source_snapshot"))
+	}
+	if c.ID != "" {
+		if _, err := uuid.Parse(c.ID); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid source_snapshot id: %s", c.ID))
+		}
+	}
+
+	return errs
+}
+
+// snapshot returns the snapshot ID or description to look up
+func (c *SourceSnapshotConfig) snapshot() string {
+	if c.ID != "" {
+		return c.ID
+	}
+	return c.Description
+}
@@ -27,8 +27,13 @@ type StateChangeConf struct {
 	Refresh   StateRefreshFunc
 	StepState multistep.StateBag
 	Target    []string
+	Timeout   time.Duration // Optional, zero waits indefinitely
 }
 
+// errStateChangeTimeout is returned by WaitForState when the optional
+// timeout expires before the target state is reached.
+var errStateChangeTimeout = errors.New("timeout while waiting for state change")
+
 // VMStateRefreshFunc returns a StateRefreshFunc that is used to watch
 // a OLVM virtual machine.
 func VMStateRefreshFunc(
@@ -156,6 +161,65 @@ func DiskStateRefreshFunc(
 	}
 }
 
+// DiskStateRefreshFuncWithWrapper returns a StateRefreshFunc that is used to
+// watch a OLVM disk with automatic reconnection support.
+func DiskStateRefreshFuncWithWrapper(
+	connWrapper *ConnectionWrapper, diskID string) StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		var resp *ovirtsdk4.DiskServiceGetResponse
+		err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			var err error
+			resp, err = conn.SystemService().
+				DisksService().
+				DiskService(diskID).
+				Get().
+				Send()
+			return err
+		})
+
+		if err != nil {
+			if _, ok := err.(*ovirtsdk4.NotFoundError); ok {
+				// Sometimes OLVM has consistency issues and doesn't see
+				// newly created Disk instance. Return empty state.
+				return nil, "", nil
+			}
+			return nil, "", err
+		}
+
+		return resp.MustDisk(), string(resp.MustDisk().MustStatus()), nil
+	}
+}
+
+// AttachedStorageDomainStateRefreshFuncWithWrapper returns a StateRefreshFunc
+// that is used to watch a OLVM storage domain attached to a data center with
+// automatic reconnection support. A detached storage domain has empty state.
+func AttachedStorageDomainStateRefreshFuncWithWrapper(
+	connWrapper *ConnectionWrapper, dataCenterID string, storageDomainID string) StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		var resp *ovirtsdk4.AttachedStorageDomainServiceGetResponse
+		err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			var err error
+			resp, err = conn.SystemService().
+				DataCentersService().
+				DataCenterService(dataCenterID).
+				StorageDomainsService().
+				StorageDomainService(storageDomainID).
+				Get().
+				Send()
+			return err
+		})
+
+		if err != nil {
+			if _, ok := err.(*ovirtsdk4.NotFoundError); ok {
+				return nil, "", nil
+			}
+			return nil, "", err
+		}
+
+		return resp.MustStorageDomain(), string(resp.MustStorageDomain().MustStatus()), nil
+	}
+}
+
 // DiskAttachmentStateRefreshFunc returns a StateRefreshFunc that is used to
 // watch a OLVM disk attachment.
 func DiskAttachmentStateRefreshFunc(
@@ -191,6 +255,11 @@ func DiskAttachmentStateRefreshFunc(
 func WaitForState(conf *StateChangeConf) (i interface{}, err error) {
 	log.Printf("Waiting for state to become: %s", conf.Target)
 
+	var deadline time.Time
+	if conf.Timeout > 0 {
+		deadline = time.Now().Add(conf.Timeout)
+	}
+
 	for {
 		var currentState string
 		i, currentState, err := conf.Refresh()
@@ -221,6 +290,10 @@ func WaitForState(conf *StateChangeConf) (i interface{}, err error) {
 			return nil, fmt.Errorf("unexpected state '%s', wanted target '%s'", currentState, conf.Target)
 		}
 
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: state still '%s' after %s, wanted target '%s'", errStateChangeTimeout, currentState, conf.Timeout, conf.Target)
+		}
+
 		log.Printf("Waiting for state to become %s, currently %s", conf.Target, currentState)
 		time.Sleep(2 * time.Second)
 	}
@@ -0,0 +1,115 @@
+package olvm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+type stepCheckDestinationTemplate struct{}
+
+func (s *stepCheckDestinationTemplate) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+
+	// Sub-versions are expected to share the name of an existing base template
+	if config.BaseTemplateName == "" {
+		if err := s.checkDestination(connWrapper, ui, config); err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+	}
+
+	// Check the publish targets before anything is created or renamed
+	for _, target := range config.PublishTargets {
+		if err := s.checkPublishTarget(connWrapper, ui, config, target); err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+// checkDestination applies on_template_exists to the destination template in
+// the data center of the build cluster
+func (s *stepCheckDestinationTemplate) checkDestination(connWrapper *ConnectionWrapper, ui packer.Ui, config *Config) error {
+	ui.Say(fmt.Sprintf("Checking whether destination template '%s' already exists...", config.DestinationTemplateName))
+
+	templates, err := findClusterTemplatesByName(connWrapper, config.Cluster, config.DestinationTemplateName)
+	if err != nil {
+		return err
+	}
+
+	if len(templates) == 0 {
+		ui.Message("Destination template does not exist")
+		return nil
+	}
+
+	switch config.OnTemplateExists {
+	case "replace":
+		ui.Message(fmt.Sprintf("Destination template exists (%d version(s)), it will be replaced", len(templates)))
+	case "rename":
+		ui.Message(fmt.Sprintf("Destination template exists (%d version(s)), it will be renamed", len(templates)))
+	case "version":
+		ui.Message(fmt.Sprintf("Destination template exists (%d version(s)), a new sub-version will be added", len(templates)))
+	default:
+		return fmt.Errorf("Destination template '%s' already exists. Set on_template_exists or use -force to replace it", config.DestinationTemplateName)
+	}
+	return nil
+}
+
+// checkPublishTarget checks that the target cluster is in the target data
+// center and applies on_template_exists to the template already published
+// there
+func (s *stepCheckDestinationTemplate) checkPublishTarget(connWrapper *ConnectionWrapper, ui packer.Ui, config *Config, target PublishTargetConfig) error {
+	ui.Say(fmt.Sprintf("Checking publish target data center '%s' (cluster '%s')...", target.DataCenter, target.Cluster))
+
+	dataCenterID, err := findDataCenterID(connWrapper, target.DataCenter)
+	if err != nil {
+		return err
+	}
+	clusterID, err := findClusterID(connWrapper, target.Cluster)
+	if err != nil {
+		return err
+	}
+	clusterDataCenterID, err := findClusterDataCenterID(connWrapper, clusterID)
+	if err != nil {
+		return err
+	}
+	if clusterDataCenterID != dataCenterID {
+		return fmt.Errorf("Publish target cluster '%s' is not in data center '%s'", target.Cluster, target.DataCenter)
+	}
+
+	templates, err := findClusterTemplatesByName(connWrapper, target.Cluster, config.DestinationTemplateName)
+	if err != nil {
+		return err
+	}
+
+	if len(templates) == 0 {
+		ui.Message(fmt.Sprintf("Template does not exist in data center '%s'", target.DataCenter))
+		return nil
+	}
+
+	switch config.OnTemplateExists {
+	case "replace":
+		if err := checkDeleteProtection(templates); err != nil {
+			return err
+		}
+		ui.Message(fmt.Sprintf("Template exists in data center '%s' (%d version(s)), it will be replaced", target.DataCenter, len(templates)))
+	case "rename":
+		ui.Message(fmt.Sprintf("Template exists in data center '%s' (%d version(s)), it will be renamed", target.DataCenter, len(templates)))
+	default:
+		return fmt.Errorf("Template '%s' already exists in data center '%s'. Set on_template_exists or use -force to replace it", config.DestinationTemplateName, target.DataCenter)
+	}
+	return nil
+}
+
+func (s *stepCheckDestinationTemplate) Cleanup(state multistep.StateBag) {
+	// Nothing to cleanup for this step
+}
@@ -0,0 +1,43 @@
+package olvm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+type stepCheckTemplatePermissions struct{}
+
+func (s *stepCheckTemplatePermissions) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+
+	// Skip if no template permissions are configured
+	if len(config.TemplatePermissions) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Validating template permissions...")
+
+	permissions, err := resolveTemplatePermissions(connWrapper, config.TemplatePermissions)
+	if err != nil {
+		err = fmt.Errorf("Error validating template permissions: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	for _, p := range permissions {
+		ui.Message(fmt.Sprintf("Template permission: %s (role %s)", p.Principal, p.Role))
+	}
+
+	state.Put("template_permissions", permissions)
+	return multistep.ActionContinue
+}
+
+func (s *stepCheckTemplatePermissions) Cleanup(state multistep.StateBag) {
+	// Nothing to cleanup for this step
+}
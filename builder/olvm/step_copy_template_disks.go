@@ -0,0 +1,180 @@
+package olvm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepCopyTemplateDisks struct{}
+
+func (s *stepCopyTemplateDisks) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+	templateID := state.Get("template_id").(string)
+
+	// Skip if no additional storage domains are configured
+	if len(config.TemplateCopyStorageDomains) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Copying template disks to storage domains: %s...", strings.Join(config.TemplateCopyStorageDomains, ", ")))
+
+	var attachmentsResp *ovirtsdk4.TemplateDiskAttachmentsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		attachmentsResp, err = conn.SystemService().
+			TemplatesService().
+			TemplateService(templateID).
+			DiskAttachmentsService().
+			List().
+			Send()
+		return err
+	})
+	if err != nil {
+		err = fmt.Errorf("Error getting template disk attachments: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	var diskIDs []string
+	if attachments, ok := attachmentsResp.Attachments(); ok {
+		for _, attachment := range attachments.Slice() {
+			diskIDs = append(diskIDs, attachment.MustDisk().MustId())
+		}
+	}
+	if len(diskIDs) == 0 {
+		ui.Say("No disks found on template")
+		return multistep.ActionContinue
+	}
+
+	// A disk is locked while it is copied, so the domains are handled one at
+	// a time while all disks are copied to each domain in parallel
+	for _, storageDomainName := range config.TemplateCopyStorageDomains {
+		storageDomainID, err := findStorageDomainID(connWrapper, storageDomainName)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+
+		// The correlation IDs tie each disk to its copy job, since the disk
+		// may still be OK right after the request
+		copying := make(map[string]string)
+		for _, diskID := range diskIDs {
+			domainIDs, err := s.getDiskStorageDomainIDs(connWrapper, diskID)
+			if err != nil {
+				ui.Error(err.Error())
+				state.Put("error", err)
+				return multistep.ActionHalt
+			}
+			if domainIDs[storageDomainID] {
+				ui.Message(fmt.Sprintf("Disk %s already exists on storage domain %s", diskID, storageDomainName))
+				continue
+			}
+
+			ui.Message(fmt.Sprintf("Copying disk %s to storage domain %s...", diskID, storageDomainName))
+			correlationID := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+			err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+				_, err := conn.SystemService().
+					DisksService().
+					DiskService(diskID).
+					Copy().
+					StorageDomain(ovirtsdk4.NewStorageDomainBuilder().Id(storageDomainID).MustBuild()).
+					Async(true).
+					Header("Correlation-Id", correlationID).
+					Send()
+				return err
+			})
+			if err != nil {
+				err = fmt.Errorf("Error copying disk %s to storage domain %s: %s", diskID, storageDomainName, err)
+				ui.Error(err.Error())
+				state.Put("error", err)
+				return multistep.ActionHalt
+			}
+			copying[diskID] = correlationID
+		}
+
+		for _, diskID := range diskIDs {
+			correlationID, ok := copying[diskID]
+			if !ok {
+				continue
+			}
+			if err := waitForJob(connWrapper, ui, state, fmt.Sprintf("Copy job step for disk %s", diskID), correlationID); err != nil {
+				err = fmt.Errorf("Error copying disk %s to storage domain %s: %s", diskID, storageDomainName, err)
+				ui.Error(err.Error())
+				state.Put("error", err)
+				return multistep.ActionHalt
+			}
+
+			diskStateChange := StateChangeConf{
+				Pending:   []string{string(ovirtsdk4.DISKSTATUS_LOCKED)},
+				Target:    []string{string(ovirtsdk4.DISKSTATUS_OK)},
+				Refresh:   DiskStateRefreshFuncWithWrapper(connWrapper, diskID),
+				StepState: state,
+			}
+			if _, err := WaitForState(&diskStateChange); err != nil {
+				err = fmt.Errorf("Error waiting for disk %s to be copied to storage domain %s: %s", diskID, storageDomainName, err)
+				ui.Error(err.Error())
+				state.Put("error", err)
+				return multistep.ActionHalt
+			}
+			log.Printf("Disk %s copied to storage domain %s", diskID, storageDomainName)
+		}
+	}
+
+	// Record the storage domains holding each template disk
+	diskDomains := make(map[string]string)
+	for _, diskID := range diskIDs {
+		domainIDs, err := s.getDiskStorageDomainIDs(connWrapper, diskID)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		var ids []string
+		for id := range domainIDs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		diskDomains[diskID] = strings.Join(ids, ",")
+	}
+	putArtifactState(state, "template_disk_storage_domains", diskDomains)
+
+	ui.Say("Successfully copied template disks")
+	return multistep.ActionContinue
+}
+
+// getDiskStorageDomainIDs returns the IDs of the storage domains holding the disk
+func (s *stepCopyTemplateDisks) getDiskStorageDomainIDs(connWrapper *ConnectionWrapper, diskID string) (map[string]bool, error) {
+	var diskResp *ovirtsdk4.DiskServiceGetResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		diskResp, err = conn.SystemService().DisksService().DiskService(diskID).Get().Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting disk %s: %s", diskID, err)
+	}
+
+	domainIDs := make(map[string]bool)
+	if storageDomains, ok := diskResp.MustDisk().StorageDomains(); ok {
+		for _, sd := range storageDomains.Slice() {
+			domainIDs[sd.MustId()] = true
+		}
+	}
+	return domainIDs, nil
+}
+
+func (s *stepCopyTemplateDisks) Cleanup(state multistep.StateBag) {
+	// Nothing to cleanup for this step
+}
@@ -3,6 +3,7 @@ package olvm
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/packer"
@@ -11,6 +12,17 @@ import (
 
 type stepCreateTemplateFromVM struct {
 	Debug bool
+
+	// Existing templates renamed out of the way by on_template_exists =
+	// "replace", removed by stepRemoveReplacedTemplates once the build
+	// succeeded
+	replacedTemplates    []*ovirtsdk4.Template
+	replacedTemplateName string
+
+	// The new template, removed again if the build fails before it is ready
+	// or while the replaced templates still need their name back
+	templateID    string
+	templateReady bool
 }
 
 func (s *stepCreateTemplateFromVM) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -89,8 +101,156 @@ func (s *stepCreateTemplateFromVM) Run(ctx context.Context, state multistep.Stat
 	}
 	templateBuilder.Cluster(cluster)
 
+	// Handle an existing destination template according to on_template_exists
+	baseTemplateName := config.BaseTemplateName
+	if baseTemplateName == "" {
+		existingTemplates, err := findClusterTemplatesByName(connWrapper, config.Cluster, config.DestinationTemplateName)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		if len(existingTemplates) > 0 {
+			switch config.OnTemplateExists {
+			case "replace":
+				// Keep the existing template until the new one is ready
+				err = checkDeleteProtection(existingTemplates)
+				if err == nil {
+					err = renameBaseTemplate(connWrapper, ui, existingTemplates, timestampedName(config.DestinationTemplateName))
+				}
+				if err == nil {
+					s.replacedTemplates = existingTemplates
+					s.replacedTemplateName = config.DestinationTemplateName
+				}
+			case "rename":
+				err = renameBaseTemplate(connWrapper, ui, existingTemplates, timestampedName(config.DestinationTemplateName))
+			case "version":
+				baseTemplateName = config.DestinationTemplateName
+			default:
+				err = fmt.Errorf("Destination template '%s' already exists", config.DestinationTemplateName)
+			}
+			if err != nil {
+				ui.Error(err.Error())
+				state.Put("error", err)
+				return multistep.ActionHalt
+			}
+		}
+	}
+
+	// Add the template as a new sub-version of the base template if specified
+	if baseTemplateName != "" {
+		ui.Say(fmt.Sprintf("Adding template as a new sub-version of base template '%s'...", baseTemplateName))
+		version, err := s.buildTemplateVersion(connWrapper, config.Cluster, baseTemplateName, config.TemplateVersionName)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		templateBuilder.Version(version)
+	}
+
+	// Set the optimization type if specified
+	if config.VMType != "" {
+		templateBuilder.Type(ovirtsdk4.VmType(config.VMType))
+	}
+
+	// Carry the operating system, custom properties and I/O settings over to the template
+	templateOS, err := buildOperatingSystem(config)
+	if err != nil {
+		err = fmt.Errorf("Error creating operating system object: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	if templateOS != nil {
+		templateBuilder.Os(templateOS)
+	}
+	customProperties, err := buildCustomProperties(config)
+	if err != nil {
+		err = fmt.Errorf("Error creating custom property object: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	if len(customProperties) > 0 {
+		templateBuilder.CustomPropertiesOfAny(customProperties...)
+	}
+	if config.IOThreads > 0 {
+		templateBuilder.Io(
+			ovirtsdk4.NewIoBuilder().
+				Threads(int64(config.IOThreads)).
+				MustBuild(),
+		)
+	}
+
+	// Set the template runtime policy
+	if config.TemplateHighAvailability {
+		haBuilder := ovirtsdk4.NewHighAvailabilityBuilder().Enabled(true)
+		if config.TemplateHAPriority > 0 {
+			haBuilder.Priority(int64(config.TemplateHAPriority))
+		}
+		templateBuilder.HighAvailability(haBuilder.MustBuild())
+		ui.Message(fmt.Sprintf("Template high availability enabled (priority: %d)", config.TemplateHAPriority))
+	}
+	if config.TemplateLeaseStorageDomain != "" {
+		leaseStorageDomainID, err := findStorageDomainID(connWrapper, config.TemplateLeaseStorageDomain)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		templateBuilder.Lease(
+			ovirtsdk4.NewStorageDomainLeaseBuilder().
+				StorageDomain(ovirtsdk4.NewStorageDomainBuilder().Id(leaseStorageDomainID).MustBuild()).
+				MustBuild(),
+		)
+		ui.Message(fmt.Sprintf("Template VM lease on storage domain: %s", config.TemplateLeaseStorageDomain))
+	}
+	if config.templateMigrationPolicyID != "" {
+		templateBuilder.Migration(
+			ovirtsdk4.NewMigrationOptionsBuilder().
+				Policy(ovirtsdk4.NewMigrationPolicyBuilder().Id(config.templateMigrationPolicyID).MustBuild()).
+				MustBuild(),
+		)
+	}
+	if config.TemplateMigrationDowntimeMs > 0 {
+		templateBuilder.MigrationDowntime(int64(config.TemplateMigrationDowntimeMs))
+	}
+	if config.TemplateStateless {
+		templateBuilder.Stateless(true)
+	}
+	if config.TemplateDeleteProtected {
+		templateBuilder.DeleteProtected(true)
+		ui.Message("Template delete protection enabled")
+	}
+
+	// The engine has no instance type on templates, which instead take the
+	// hardware settings instance_type gave the build VM along with the rest
+	// of its configuration
+
+	// Set the quota and CPU profile resolved for the build VM
+	var quotaID string
+	if rawQuotaID, ok := state.GetOk("quota_id"); ok {
+		quotaID = rawQuotaID.(string)
+		templateBuilder.Quota(ovirtsdk4.NewQuotaBuilder().Id(quotaID).MustBuild())
+	}
+	if cpuProfileID, ok := state.GetOk("cpu_profile_id"); ok {
+		templateBuilder.CpuProfile(ovirtsdk4.NewCpuProfileBuilder().Id(cpuProfileID.(string)).MustBuild())
+	}
+
 	// Set the source VM
 	vmBuilder := ovirtsdk4.NewVmBuilder().Id(vmID)
+
+	// Carry quota and disk profile over to the template disks
+	if quotaID != "" || config.DiskProfile != "" {
+		diskAttachments, err := s.getProfiledVMDisks(connWrapper, config, vmID, quotaID)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		vmBuilder.DiskAttachmentsOfAny(diskAttachments...)
+	}
 	templateBuilder.VmBuilder(vmBuilder)
 
 	template, err := templateBuilder.Build()
@@ -129,6 +289,7 @@ func (s *stepCreateTemplateFromVM) Run(ctx context.Context, state multistep.Stat
 	}
 
 	templateID := createdTemplate.MustId()
+	s.templateID = templateID
 	ui.Say(fmt.Sprintf("Template created with ID: %s", templateID))
 
 	// Wait for template to reach OK state
@@ -139,13 +300,61 @@ func (s *stepCreateTemplateFromVM) Run(ctx context.Context, state multistep.Stat
 		Refresh:   TemplateStateRefreshFuncWithWrapper(connWrapper, templateID),
 		StepState: state,
 	}
-	if _, err := WaitForState(&templateStateChange); err != nil {
+	result, err := WaitForState(&templateStateChange)
+	if err != nil {
 		err := fmt.Errorf("Error waiting for template (%s) to reach OK state: %s", templateID, err)
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
+	// Record the template version
+	if version, ok := result.(*ovirtsdk4.Template).Version(); ok {
+		if versionNumber, ok := version.VersionNumber(); ok {
+			ui.Message(fmt.Sprintf("Template version number: %d", versionNumber))
+			putArtifactState(state, "template_version_number", versionNumber)
+		}
+		if versionName, ok := version.VersionName(); ok {
+			putArtifactState(state, "template_version_name", versionName)
+		}
+	}
+
+	if len(config.TemplateTags) > 0 {
+		tags, err := renderTags(config, config.TemplateTags)
+		if err == nil {
+			ui.Message(fmt.Sprintf("Tagging template: %v", tags))
+			err = assignTags(connWrapper, tags, func(conn *ovirtsdk4.Connection) *ovirtsdk4.AssignedTagsService {
+				return conn.SystemService().TemplatesService().TemplateService(templateID).TagsService()
+			})
+		}
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		putArtifactState(state, "template_tags", tags)
+	}
+
+	if permissions, ok := state.GetOk("template_permissions"); ok {
+		ui.Message("Granting template permissions...")
+		if err := assignTemplatePermissions(connWrapper, templateID, permissions.([]templatePermission)); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+	s.templateReady = true
+
+	// The replaced templates are only removed once the remaining steps
+	// succeeded, until then the build can still give them their name back
+	if len(s.replacedTemplates) > 0 {
+		var replaced []*ovirtsdk4.Template
+		if rawReplaced, ok := state.GetOk("replaced_templates"); ok {
+			replaced = rawReplaced.([]*ovirtsdk4.Template)
+		}
+		state.Put("replaced_templates", append(replaced, s.replacedTemplates...))
+	}
+
 	ui.Say(fmt.Sprintf("Successfully created template '%s' (ID: %s)", config.DestinationTemplateName, templateID))
 
 	// Store the template name and ID in state for potential use by other steps
@@ -155,6 +364,95 @@ func (s *stepCreateTemplateFromVM) Run(ctx context.Context, state multistep.Stat
 	return multistep.ActionContinue
 }
 
+// buildTemplateVersion returns the version settings that add the template as
+// a new sub-version of the named base template
+func (s *stepCreateTemplateFromVM) buildTemplateVersion(connWrapper *ConnectionWrapper, clusterName, baseTemplateName, versionName string) (*ovirtsdk4.TemplateVersion, error) {
+	templates, err := findClusterTemplatesByName(connWrapper, clusterName, baseTemplateName)
+	if err != nil {
+		return nil, err
+	}
+
+	// The base template is the root of the version chain (version 1)
+	var baseTemplateID string
+	for _, tp := range templates {
+		if templateVersionNumber(tp) == 1 {
+			baseTemplateID = tp.MustId()
+			break
+		}
+	}
+	if baseTemplateID == "" {
+		return nil, fmt.Errorf("Could not find base template '%s'", baseTemplateName)
+	}
+	log.Printf("Using base template id: %s", baseTemplateID)
+
+	versionBuilder := ovirtsdk4.NewTemplateVersionBuilder().
+		BaseTemplate(
+			ovirtsdk4.NewTemplateBuilder().
+				Id(baseTemplateID).
+				MustBuild(),
+		)
+	if versionName != "" {
+		versionBuilder.VersionName(versionName)
+	}
+
+	version, err := versionBuilder.Build()
+	if err != nil {
+		return nil, fmt.Errorf("Error creating template version object: %s", err)
+	}
+	return version, nil
+}
+
+func (s *stepCreateTemplateFromVM) getProfiledVMDisks(connWrapper *ConnectionWrapper, config *Config, vmID, quotaID string) ([]*ovirtsdk4.DiskAttachment, error) {
+	var attachmentsResp *ovirtsdk4.DiskAttachmentsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		attachmentsResp, err = conn.SystemService().
+			VmsService().
+			VmService(vmID).
+			DiskAttachmentsService().
+			List().
+			Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting VM disk attachments: %s", err)
+	}
+
+	var diskAttachments []*ovirtsdk4.DiskAttachment
+	if attachments, ok := attachmentsResp.Attachments(); ok {
+		for _, attachment := range attachments.Slice() {
+			disk, err := buildProfiledDisk(connWrapper, config, attachment.MustDisk().MustId(), quotaID)
+			if err != nil {
+				return nil, err
+			}
+			diskAttachments = append(diskAttachments, ovirtsdk4.NewDiskAttachmentBuilder().
+				Disk(disk).
+				MustBuild())
+		}
+	}
+
+	return diskAttachments, nil
+}
+
 func (s *stepCreateTemplateFromVM) Cleanup(state multistep.StateBag) {
-	// Nothing to cleanup for this step
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+
+	if !buildFailed(state) {
+		return
+	}
+
+	// The new template holds the name of the replaced templates, so it is
+	// removed before they get their name back
+	if s.templateID != "" && (!s.templateReady || len(s.replacedTemplates) > 0) {
+		discardTemplate(connWrapper, ui, config, s.templateID)
+		s.templateID = ""
+	}
+	if len(s.replacedTemplates) > 0 {
+		if err := renameBaseTemplate(connWrapper, ui, s.replacedTemplates, s.replacedTemplateName); err != nil {
+			ui.Error(err.Error())
+		}
+		s.replacedTemplates = nil
+	}
 }
@@ -16,14 +16,21 @@ import (
 type stepCreateVM struct {
 	Debug bool
 	Ctx   interpolate.Context
+
+	// Temporary snapshot of the source VM the build VM is cloned from
+	temporarySnapshotVMID string
+	temporarySnapshotID   string
 }
 
-// VMResourceInfo holds information about the source resource (template or disk)
+// VMResourceInfo holds information about the source resource (template, disk, VM, snapshot or OVA)
 type VMResourceInfo struct {
-	ID       string
-	Name     string
-	CPUCount int
-	MemoryMB int
+	ID         string
+	Name       string
+	CPUCount   int
+	MemoryMB   int
+	SnapshotID string // Snapshot of the source VM to clone from, if any
+
+	HardwareTemplateID string // Template providing the hardware of a disk source, if any
 }
 
 func (s *stepCreateVM) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -35,21 +42,71 @@ func (s *stepCreateVM) Run(ctx context.Context, state multistep.StateBag) multis
 	ui.Say(fmt.Sprintf("Creating virtual machine from %s...", sourceType))
 
 	// Get cluster ID
-	clusterID, err := s.getClusterID(connWrapper, config.Cluster)
+	clusterID, err := findClusterID(connWrapper, config.Cluster)
 	if err != nil {
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
-	// Get source resource info (template or disk)
-	resourceInfo, err := s.getSourceResourceInfo(connWrapper, config)
+	// Resolve quota and CPU profile if specified
+	var quotaID, cpuProfileID string
+	if config.Quota != "" {
+		quotaID, err = findQuotaID(connWrapper, clusterID, config.Quota)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		state.Put("quota_id", quotaID)
+	}
+	if config.CPUProfile != "" {
+		cpuProfileID, err = findCPUProfileID(connWrapper, clusterID, config.CPUProfile)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		state.Put("cpu_profile_id", cpuProfileID)
+	}
+
+	// Get source resource info (template, disk, VM, snapshot or OVA)
+	resourceInfo, err := s.getSourceResourceInfo(connWrapper, config, clusterID, state)
 	if err != nil {
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
+	// The clone action ignores the cluster and disk settings, so a source VM
+	// without a snapshot is cloned from a temporary snapshot instead
+	if sourceType == "vm" && resourceInfo.SnapshotID == "" {
+		ui.Message(fmt.Sprintf("Creating temporary snapshot of VM '%s'...", resourceInfo.Name))
+		snapshotID, err := createVMSnapshot(connWrapper, ui, state, resourceInfo.ID, fmt.Sprintf("Packer clone source for %s", config.VMName))
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		s.temporarySnapshotVMID = resourceInfo.ID
+		s.temporarySnapshotID = snapshotID
+		resourceInfo.SnapshotID = snapshotID
+	}
+
+	// Get instance type info if specified, its resources replace the source defaults
+	var instanceTypeID string
+	if config.InstanceType != "" {
+		instanceTypeInfo, err := s.getInstanceTypeInfo(connWrapper, config.InstanceType)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		instanceTypeID = instanceTypeInfo.ID
+		resourceInfo.CPUCount = instanceTypeInfo.CPUCount
+		resourceInfo.MemoryMB = instanceTypeInfo.MemoryMB
+	}
+
 	// Determine CPU and memory values
 	cpuCount, memoryMB := s.getVMResources(config, resourceInfo)
 
@@ -57,12 +114,45 @@ func (s *stepCreateVM) Run(ctx context.Context, state multistep.StateBag) multis
 	log.Printf("VM memory: %d MB", memoryMB)
 
 	// Create VM
-	vmID, err := s.createVM(connWrapper, config, clusterID, cpuCount, memoryMB, resourceInfo)
+	vmID, err := s.createVM(connWrapper, config, clusterID, instanceTypeID, quotaID, cpuProfileID, cpuCount, memoryMB, resourceInfo)
 	if err != nil {
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
+	// Cleanup removes the VM from here on, whichever of the following fails
+	state.Put("vm_id", vmID)
+
+	// Add VM to affinity groups and labels if specified
+	if len(config.AffinityGroups) > 0 {
+		if err := s.addToAffinityGroups(connWrapper, clusterID, vmID, config.AffinityGroups); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+	if len(config.AffinityLabels) > 0 {
+		if err := s.addToAffinityLabels(connWrapper, vmID, config.AffinityLabels); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	if len(config.VMTags) > 0 {
+		tags, err := renderTags(config, config.VMTags)
+		if err == nil {
+			ui.Message(fmt.Sprintf("Tagging VM: %v", tags))
+			err = assignTags(connWrapper, tags, func(conn *ovirtsdk4.Connection) *ovirtsdk4.AssignedTagsService {
+				return conn.SystemService().VmsService().VmService(vmID).TagsService()
+			})
+		}
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
 
 	// Attach network if specified
 	if config.NetworkName != "" {
@@ -80,98 +170,61 @@ func (s *stepCreateVM) Run(ctx context.Context, state multistep.StateBag) multis
 		return multistep.ActionHalt
 	}
 
-	// Get the latest VM info
-	var vmResp *ovirtsdk4.VmServiceGetResponse
-	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
-		var err error
-		vmResp, err = conn.SystemService().
-			VmsService().
-			VmService(vmID).
-			Get().
-			Send()
-		return err
-	})
-
-	if err != nil {
-		err = fmt.Errorf("Error getting VM info: %s", err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
-	}
-
-	latestVM := vmResp.MustVm()
-	state.Put("vm_id", latestVM.MustId())
+	// The disks are copied, the temporary snapshot is no longer needed
+	s.removeTemporarySnapshot(connWrapper, ui, state)
 
 	return multistep.ActionContinue
 }
 
-func (s *stepCreateVM) getClusterID(connWrapper *ConnectionWrapper, clusterName string) (string, error) {
-	var cResp *ovirtsdk4.ClustersServiceListResponse
-	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
-		var err error
-		cResp, err = conn.SystemService().
-			ClustersService().
-			List().
-			Send()
-		return err
-	})
-	if err != nil {
-		return "", fmt.Errorf("Error getting cluster list: %s", err)
-	}
-
-	if clusters, ok := cResp.Clusters(); ok {
-		for _, cluster := range clusters.Slice() {
-			if name, ok := cluster.Name(); ok {
-				if name == clusterName {
-					clusterID := cluster.MustId()
-					log.Printf("Using cluster id: %s", clusterID)
-					return clusterID, nil
-				}
-			}
-		}
-	}
-
-	return "", fmt.Errorf("Could not find cluster '%s'", clusterName)
-}
-
-func (s *stepCreateVM) getSourceResourceInfo(connWrapper *ConnectionWrapper, config *Config) (*VMResourceInfo, error) {
+func (s *stepCreateVM) getSourceResourceInfo(connWrapper *ConnectionWrapper, config *Config, clusterID string, state multistep.StateBag) (*VMResourceInfo, error) {
 	switch config.SourceConfig.GetSourceType() {
 	case "template":
-		return s.getTemplateInfo(connWrapper, config)
+		return s.getTemplateInfo(connWrapper, config, clusterID)
 	case "disk":
-		return s.getDiskInfo(connWrapper, config)
+		return s.getDiskInfo(connWrapper, config, clusterID)
+	case "vm":
+		return s.getVMInfo(connWrapper, config, clusterID, config.SourceVMName, config.SourceVMID, config.SourceVMSnapshot)
+	case "snapshot":
+		sourceSnapshot := config.SourceSnapshot
+		return s.getVMInfo(connWrapper, config, clusterID, sourceSnapshot.VMName, sourceSnapshot.VMID, sourceSnapshot.snapshot())
+	case "ova":
+		// The OVA was imported into a VM by stepImportOVA
+		return s.getVMInfo(connWrapper, config, clusterID, "", state.Get("ova_vm_id").(string), "")
 	default:
 		return nil, fmt.Errorf("Unsupported source type: %s", config.SourceConfig.GetSourceType())
 	}
 }
 
-func (s *stepCreateVM) getTemplateInfo(connWrapper *ConnectionWrapper, config *Config) (*VMResourceInfo, error) {
+// templateDataCenter returns the data center source and hardware templates
+// are looked up in, which is the data center of the cluster. data_center must
+// match it when set.
+func (s *stepCreateVM) templateDataCenter(connWrapper *ConnectionWrapper, config *Config, clusterID string) (string, error) {
+	clusterDataCenterNames, err := findClusterDataCenterNames(connWrapper)
+	if err != nil {
+		return "", err
+	}
+	dataCenterName := clusterDataCenterNames[clusterID]
+	if config.DataCenter != "" && config.DataCenter != dataCenterName {
+		return "", fmt.Errorf("Cluster %s is not in data center %s", config.Cluster, config.DataCenter)
+	}
+	log.Printf("Looking up templates in data center: %s", dataCenterName)
+	return dataCenterName, nil
+}
+
+func (s *stepCreateVM) getTemplateInfo(connWrapper *ConnectionWrapper, config *Config, clusterID string) (*VMResourceInfo, error) {
 	var templateID string
 	if config.SourceTemplateID != "" {
 		templateID = config.SourceTemplateID
 	} else {
-		var tpsResp *ovirtsdk4.TemplatesServiceListResponse
-		err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
-			var err error
-			tpsResp, err = conn.SystemService().TemplatesService().List().
-				Search(fmt.Sprintf("name=%s", config.SourceTemplateName)).
-				Send()
-			return err
-		})
+		dataCenterName, err := s.templateDataCenter(connWrapper, config, clusterID)
 		if err != nil {
-			return nil, fmt.Errorf("Error searching templates: %s", err)
+			return nil, err
 		}
-		tpSlice, _ := tpsResp.Templates()
-
-		for _, tp := range tpSlice.Slice() {
-			if tp.MustVersion().MustVersionNumber() == int64(config.SourceTemplateVersion) {
-				templateID = tp.MustId()
-				break
-			}
-		}
-		if templateID == "" {
-			return nil, fmt.Errorf("Could not find template '%s' with version '%d'", config.SourceTemplateName, config.SourceTemplateVersion)
+		template, err := findSourceTemplate(connWrapper, config.SourceTemplateName, config.sourceTemplateVersionNumber, dataCenterName)
+		if err != nil {
+			return nil, err
 		}
+		templateID = template.MustId()
 	}
 	log.Printf("Using template id: %s", templateID)
 
@@ -215,7 +268,7 @@ func (s *stepCreateVM) getTemplateInfo(connWrapper *ConnectionWrapper, config *C
 	}, nil
 }
 
-func (s *stepCreateVM) getDiskInfo(connWrapper *ConnectionWrapper, config *Config) (*VMResourceInfo, error) {
+func (s *stepCreateVM) getDiskInfo(connWrapper *ConnectionWrapper, config *Config, clusterID string) (*VMResourceInfo, error) {
 	var diskID string
 	var diskFound bool
 	if config.SourceDiskID != "" {
@@ -295,11 +348,141 @@ func (s *stepCreateVM) getDiskInfo(connWrapper *ConnectionWrapper, config *Confi
 	disk := diskResp.MustDisk()
 	log.Printf("Found disk: %s (Size: %d bytes)", disk.MustName(), disk.MustProvisionedSize())
 
-	return &VMResourceInfo{
+	resourceInfo := &VMResourceInfo{
 		ID:       diskID,
 		Name:     config.SourceDiskName,
 		CPUCount: 1,    // default for disk
 		MemoryMB: 1024, // default for disk
+	}
+
+	// The latest version of the hardware template provides the resources
+	if config.HardwareTemplateName != "" {
+		dataCenterName, err := s.templateDataCenter(connWrapper, config, clusterID)
+		if err != nil {
+			return nil, err
+		}
+		template, err := findSourceTemplate(connWrapper, config.HardwareTemplateName, 0, dataCenterName)
+		if err != nil {
+			return nil, err
+		}
+		resourceInfo.HardwareTemplateID = template.MustId()
+		cpu, _ := template.Cpu()
+		resourceInfo.CPUCount = vmCPUCount(cpu)
+		if memory, ok := template.Memory(); ok && memory > 0 {
+			resourceInfo.MemoryMB = int(memory / (1024 * 1024)) // Convert bytes to MB
+		}
+		log.Printf("Using hardware template id: %s (CPU: %d, memory: %d MB)", resourceInfo.HardwareTemplateID, resourceInfo.CPUCount, resourceInfo.MemoryMB)
+	}
+
+	return resourceInfo, nil
+}
+
+func (s *stepCreateVM) getVMInfo(connWrapper *ConnectionWrapper, config *Config, clusterID, vmName, vmID, snapshot string) (*VMResourceInfo, error) {
+	vm, err := findVM(connWrapper, vmName, vmID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Disks are only copied within a data center
+	if vmCluster, ok := vm.Cluster(); ok && vmCluster.MustId() != clusterID {
+		vmDataCenterID, err := findClusterDataCenterID(connWrapper, vmCluster.MustId())
+		if err != nil {
+			return nil, err
+		}
+		dataCenterID, err := findClusterDataCenterID(connWrapper, clusterID)
+		if err != nil {
+			return nil, err
+		}
+		if vmDataCenterID != dataCenterID {
+			return nil, fmt.Errorf("Source VM '%s' is not in the data center of cluster %s", vm.MustName(), config.Cluster)
+		}
+	}
+
+	resourceInfo := &VMResourceInfo{
+		ID:   vm.MustId(),
+		Name: vm.MustName(),
+	}
+	cpu, _ := vm.Cpu()
+	memory, _ := vm.Memory()
+
+	if snapshot != "" {
+		// Clone from the snapshot, using the VM configuration stored with it
+		vmSnapshot, err := findVMSnapshot(connWrapper, resourceInfo.ID, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		resourceInfo.SnapshotID = vmSnapshot.MustId()
+		if snapshotCpu, ok := vmSnapshot.Cpu(); ok {
+			cpu = snapshotCpu
+		}
+		if snapshotMemory, ok := vmSnapshot.Memory(); ok && snapshotMemory > 0 {
+			memory = snapshotMemory
+		}
+	} else if status, ok := vm.Status(); ok && status != ovirtsdk4.VMSTATUS_DOWN {
+		// The engine only clones a VM which is down, set a snapshot for others
+		return nil, fmt.Errorf("Source VM '%s' must be down to be cloned (currently %s), set source_vm_snapshot to clone from a snapshot instead", resourceInfo.Name, status)
+	}
+
+	resourceInfo.CPUCount = vmCPUCount(cpu)
+	resourceInfo.MemoryMB = int(memory / (1024 * 1024)) // Convert bytes to MB
+	if resourceInfo.MemoryMB == 0 {
+		resourceInfo.MemoryMB = 1024 // fallback default
+	}
+
+	log.Printf("Found VM: %s (CPU: %d, memory: %d MB)", resourceInfo.Name, resourceInfo.CPUCount, resourceInfo.MemoryMB)
+	return resourceInfo, nil
+}
+
+func (s *stepCreateVM) getInstanceTypeInfo(connWrapper *ConnectionWrapper, instanceTypeName string) (*VMResourceInfo, error) {
+	var itsResp *ovirtsdk4.InstanceTypesServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		itsResp, err = conn.SystemService().InstanceTypesService().List().
+			Search(fmt.Sprintf("name=%s", instanceTypeName)).
+			Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error searching instance types: %s", err)
+	}
+
+	var instanceType *ovirtsdk4.InstanceType
+	if itSlice, ok := itsResp.InstanceType(); ok {
+		for _, it := range itSlice.Slice() {
+			if name, ok := it.Name(); ok && name == instanceTypeName {
+				instanceType = it
+				break
+			}
+		}
+	}
+	if instanceType == nil {
+		return nil, fmt.Errorf("Could not find instance type '%s'", instanceTypeName)
+	}
+
+	// Extract CPU and memory info
+	cpuCount := 1 // default
+	if itCpu, ok := instanceType.Cpu(); ok {
+		if itCpuTopology, ok := itCpu.Topology(); ok {
+			sockets, _ := itCpuTopology.Sockets()
+			cores, _ := itCpuTopology.Cores()
+			if sockets*cores > 0 {
+				cpuCount = int(sockets * cores)
+			}
+		}
+	}
+
+	memoryMB := 1024 // default
+	if itMemory, ok := instanceType.Memory(); ok && itMemory > 0 {
+		memoryMB = int(itMemory / (1024 * 1024)) // Convert bytes to MB
+	}
+
+	log.Printf("Using instance type id: %s (CPU: %d, memory: %d MB)", instanceType.MustId(), cpuCount, memoryMB)
+
+	return &VMResourceInfo{
+		ID:       instanceType.MustId(),
+		Name:     instanceTypeName,
+		CPUCount: cpuCount,
+		MemoryMB: memoryMB,
 	}, nil
 }
 
@@ -318,7 +501,7 @@ func (s *stepCreateVM) getVMResources(config *Config, resourceInfo *VMResourceIn
 	return cpuCount, memoryMB
 }
 
-func (s *stepCreateVM) createVM(connWrapper *ConnectionWrapper, config *Config, clusterID string, cpuCount, memoryMB int, resourceInfo *VMResourceInfo) (string, error) {
+func (s *stepCreateVM) createVM(connWrapper *ConnectionWrapper, config *Config, clusterID, instanceTypeID, quotaID, cpuProfileID string, cpuCount, memoryMB int, resourceInfo *VMResourceInfo) (string, error) {
 	vmBuilder := ovirtsdk4.NewVmBuilder().
 		Name(config.VMName).
 		Cpu(
@@ -333,6 +516,60 @@ func (s *stepCreateVM) createVM(connWrapper *ConnectionWrapper, config *Config,
 		).
 		Memory(int64(memoryMB) * 1024 * 1024) // Convert MB to bytes
 
+	// Set instance type and optimization type if specified
+	if instanceTypeID != "" {
+		vmBuilder.InstanceType(
+			ovirtsdk4.NewInstanceTypeBuilder().
+				Id(instanceTypeID).
+				MustBuild(),
+		)
+	}
+	if config.VMType != "" {
+		vmBuilder.Type(ovirtsdk4.VmType(config.VMType))
+	}
+
+	// Set operating system type and boot order if specified
+	vmOS, err := buildOperatingSystem(config)
+	if err != nil {
+		return "", fmt.Errorf("Error creating operating system object: %s", err)
+	}
+	if vmOS != nil {
+		vmBuilder.Os(vmOS)
+	}
+
+	// Set custom properties and I/O threads if specified
+	customProperties, err := buildCustomProperties(config)
+	if err != nil {
+		return "", fmt.Errorf("Error creating custom property object: %s", err)
+	}
+	if len(customProperties) > 0 {
+		vmBuilder.CustomPropertiesOfAny(customProperties...)
+	}
+	if config.IOThreads > 0 {
+		vmBuilder.Io(
+			ovirtsdk4.NewIoBuilder().
+				Threads(int64(config.IOThreads)).
+				MustBuild(),
+		)
+	}
+
+	// Set quota and CPU profile if specified
+	if quotaID != "" {
+		vmBuilder.Quota(ovirtsdk4.NewQuotaBuilder().Id(quotaID).MustBuild())
+	}
+	if cpuProfileID != "" {
+		vmBuilder.CpuProfile(ovirtsdk4.NewCpuProfileBuilder().Id(cpuProfileID).MustBuild())
+	}
+
+	// Set host placement if specified
+	if len(config.Hosts) > 0 || config.PlacementAffinity != "" {
+		placementPolicy, err := s.getPlacementPolicy(connWrapper, config)
+		if err != nil {
+			return "", err
+		}
+		vmBuilder.PlacementPolicy(placementPolicy)
+	}
+
 	cluster, err := ovirtsdk4.NewClusterBuilder().
 		Id(clusterID).
 		Build()
@@ -359,9 +596,38 @@ func (s *stepCreateVM) createVM(connWrapper *ConnectionWrapper, config *Config,
 			return "", fmt.Errorf("Error creating VirtIO-SCSI object: %s", err)
 		}
 		vmBuilder.VirtioScsi(virtioScsi)
+
+		// Carry quota and disk profile over to the disks copied from the template
+		if quotaID != "" || config.DiskProfile != "" {
+			diskAttachments, err := s.getProfiledTemplateDisks(connWrapper, config, resourceInfo.ID, quotaID)
+			if err != nil {
+				return "", err
+			}
+			vmBuilder.DiskAttachmentsOfAny(diskAttachments...)
+		}
 	}
 
-	if config.SourceConfig.GetSourceType() == "disk" {
+	if config.SourceConfig.GetSourceType() == "disk" && resourceInfo.HardwareTemplateID != "" {
+		// Take the non-disk settings from the hardware template, its disks
+		// are removed after VM creation
+		hardwareTemplate, err := ovirtsdk4.NewTemplateBuilder().
+			Id(resourceInfo.HardwareTemplateID).
+			Build()
+		if err != nil {
+			return "", fmt.Errorf("Error creating hardware template object: %s", err)
+		}
+		vmBuilder.Template(hardwareTemplate)
+
+		virtioScsi, err := ovirtsdk4.NewVirtioScsiBuilder().
+			Enabled(config.VMStorageDriver == "virtio-scsi").
+			Build()
+		if err != nil {
+			return "", fmt.Errorf("Error creating VirtIO-SCSI object: %s", err)
+		}
+		vmBuilder.VirtioScsi(virtioScsi)
+
+		log.Printf("Creating VM from hardware template %s for disk ID: %s", resourceInfo.HardwareTemplateID, resourceInfo.ID)
+	} else if config.SourceConfig.GetSourceType() == "disk" {
 		// For disk-based VMs, we need to use the blank template
 		blankTemplate, err := ovirtsdk4.NewTemplateBuilder().
 			Name("Blank").
@@ -375,11 +641,49 @@ func (s *stepCreateVM) createVM(connWrapper *ConnectionWrapper, config *Config,
 		// Note: Disk will be attached after VM creation
 	}
 
+	// A snapshot of the source VM is cloned with its disks, NICs and hardware
+	// settings by adding the VM from that snapshot
+	if resourceInfo.SnapshotID != "" {
+		log.Printf("Cloning VM %s from snapshot %s", resourceInfo.ID, resourceInfo.SnapshotID)
+		vmBuilder.SnapshotsOfAny(
+			ovirtsdk4.NewSnapshotBuilder().
+				Id(resourceInfo.SnapshotID).
+				MustBuild(),
+		)
+
+		// Carry quota and disk profile over to the disks copied from the snapshot
+		if quotaID != "" || config.DiskProfile != "" {
+			diskAttachments, err := s.getProfiledSnapshotDisks(connWrapper, config, resourceInfo.ID, resourceInfo.SnapshotID, quotaID)
+			if err != nil {
+				return "", err
+			}
+			vmBuilder.DiskAttachmentsOfAny(diskAttachments...)
+		}
+	}
+
 	vm, err := vmBuilder.Build()
 	if err != nil {
 		return "", fmt.Errorf("Error creating VM object: %s", err)
 	}
 
+	// The imported OVA is the build VM, apply the build VM settings to it
+	if config.SourceConfig.GetSourceType() == "ova" {
+		log.Printf("Updating imported virtual machine %s", resourceInfo.ID)
+		err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			_, err := conn.SystemService().
+				VmsService().
+				VmService(resourceInfo.ID).
+				Update().
+				Vm(vm).
+				Send()
+			return err
+		})
+		if err != nil {
+			return "", fmt.Errorf("Error updating imported virtual machine: %s", err)
+		}
+		return resourceInfo.ID, nil
+	}
+
 	var vmAddResp *ovirtsdk4.VmsServiceAddResponse
 	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
 		var err error
@@ -409,8 +713,14 @@ func (s *stepCreateVM) createVM(connWrapper *ConnectionWrapper, config *Config,
 
 	// Attach disk for disk-based VMs after VM creation
 	if config.SourceConfig.GetSourceType() == "disk" {
+		if resourceInfo.HardwareTemplateID != "" {
+			if err := s.removeVMDisks(connWrapper, vmID); err != nil {
+				return "", err
+			}
+		}
+
 		log.Printf("Cloning disk %s before attaching to VM %s", resourceInfo.ID, vmID)
-		clonedDiskID, err := s.cloneDisk(connWrapper, resourceInfo.ID, resourceInfo.Name)
+		clonedDiskID, err := s.cloneDisk(connWrapper, config, resourceInfo.ID, resourceInfo.Name, quotaID)
 		if err != nil {
 			return "", fmt.Errorf("Error cloning disk: %s", err)
 		}
@@ -453,7 +763,270 @@ func (s *stepCreateVM) createVM(connWrapper *ConnectionWrapper, config *Config,
 	return vmID, nil
 }
 
-func (s *stepCreateVM) cloneDisk(connWrapper *ConnectionWrapper, sourceDiskID, sourceDiskName string) (string, error) {
+// removeVMDisks removes the disks a VM was created with from its template
+func (s *stepCreateVM) removeVMDisks(connWrapper *ConnectionWrapper, vmID string) error {
+	if err := s.waitForVMReady(connWrapper, vmID, nil); err != nil {
+		return err
+	}
+
+	var attachmentsResp *ovirtsdk4.DiskAttachmentsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		attachmentsResp, err = conn.SystemService().VmsService().VmService(vmID).DiskAttachmentsService().List().Send()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Error getting VM disk attachments: %s", err)
+	}
+
+	attachments, ok := attachmentsResp.Attachments()
+	if !ok {
+		return nil
+	}
+	for _, attachment := range attachments.Slice() {
+		diskID := attachment.MustId()
+		log.Printf("Removing hardware template disk %s from VM %s", diskID, vmID)
+		err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			_, err := conn.SystemService().
+				VmsService().
+				VmService(vmID).
+				DiskAttachmentsService().
+				AttachmentService(diskID).
+				Remove().
+				DetachOnly(false).
+				Send()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Error removing hardware template disk %s: %s", diskID, err)
+		}
+
+		diskStateChange := StateChangeConf{
+			Pending: []string{string(ovirtsdk4.DISKSTATUS_OK), string(ovirtsdk4.DISKSTATUS_LOCKED)},
+			Target:  []string{""},
+			Refresh: DiskStateRefreshFuncWithWrapper(connWrapper, diskID),
+		}
+		if _, err := WaitForState(&diskStateChange); err != nil {
+			return fmt.Errorf("Error waiting for hardware template disk %s to be removed: %s", diskID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *stepCreateVM) getPlacementPolicy(connWrapper *ConnectionWrapper, config *Config) (*ovirtsdk4.VmPlacementPolicy, error) {
+	placementBuilder := ovirtsdk4.NewVmPlacementPolicyBuilder()
+
+	for _, hostName := range config.Hosts {
+		var hostsResp *ovirtsdk4.HostsServiceListResponse
+		err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			var err error
+			hostsResp, err = conn.SystemService().HostsService().List().
+				Search(fmt.Sprintf("name=%s", hostName)).
+				Send()
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error searching for host %s: %s", hostName, err)
+		}
+
+		var hostID string
+		if hosts, ok := hostsResp.Hosts(); ok {
+			for _, host := range hosts.Slice() {
+				if name, ok := host.Name(); ok && name == hostName {
+					hostID = host.MustId()
+					break
+				}
+			}
+		}
+		if hostID == "" {
+			return nil, fmt.Errorf("Could not find host '%s'", hostName)
+		}
+
+		log.Printf("Placing VM on host: %s (ID: %s)", hostName, hostID)
+		placementBuilder.HostsOfAny(
+			ovirtsdk4.NewHostBuilder().
+				Id(hostID).
+				MustBuild(),
+		)
+	}
+
+	if config.PlacementAffinity != "" {
+		log.Printf("Using placement affinity: %s", config.PlacementAffinity)
+		placementBuilder.Affinity(ovirtsdk4.VmAffinity(config.PlacementAffinity))
+	}
+
+	placementPolicy, err := placementBuilder.Build()
+	if err != nil {
+		return nil, fmt.Errorf("Error creating placement policy object: %s", err)
+	}
+	return placementPolicy, nil
+}
+
+func (s *stepCreateVM) addToAffinityGroups(connWrapper *ConnectionWrapper, clusterID, vmID string, groupNames []string) error {
+	var groupsResp *ovirtsdk4.AffinityGroupsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		groupsResp, err = conn.SystemService().
+			ClustersService().
+			ClusterService(clusterID).
+			AffinityGroupsService().
+			List().
+			Send()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Error getting affinity groups: %s", err)
+	}
+
+	groupIDs := make(map[string]string)
+	if groups, ok := groupsResp.Groups(); ok {
+		for _, group := range groups.Slice() {
+			if name, ok := group.Name(); ok {
+				groupIDs[name] = group.MustId()
+			}
+		}
+	}
+
+	for _, groupName := range groupNames {
+		groupID, ok := groupIDs[groupName]
+		if !ok {
+			return fmt.Errorf("Could not find affinity group '%s' in cluster", groupName)
+		}
+
+		log.Printf("Adding VM %s to affinity group: %s (ID: %s)", vmID, groupName, groupID)
+		err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			_, err := conn.SystemService().
+				ClustersService().
+				ClusterService(clusterID).
+				AffinityGroupsService().
+				GroupService(groupID).
+				VmsService().
+				Add().
+				Vm(ovirtsdk4.NewVmBuilder().Id(vmID).MustBuild()).
+				Send()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Error adding VM to affinity group %s: %s", groupName, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *stepCreateVM) addToAffinityLabels(connWrapper *ConnectionWrapper, vmID string, labelNames []string) error {
+	var labelsResp *ovirtsdk4.AffinityLabelsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		labelsResp, err = conn.SystemService().AffinityLabelsService().List().Send()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Error getting affinity labels: %s", err)
+	}
+
+	labelIDs := make(map[string]string)
+	if labels, ok := labelsResp.Labels(); ok {
+		for _, label := range labels.Slice() {
+			if name, ok := label.Name(); ok {
+				labelIDs[name] = label.MustId()
+			}
+		}
+	}
+
+	for _, labelName := range labelNames {
+		labelID, ok := labelIDs[labelName]
+		if !ok {
+			return fmt.Errorf("Could not find affinity label '%s'", labelName)
+		}
+
+		log.Printf("Adding VM %s to affinity label: %s (ID: %s)", vmID, labelName, labelID)
+		err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			_, err := conn.SystemService().
+				AffinityLabelsService().
+				LabelService(labelID).
+				VmsService().
+				Add().
+				Vm(ovirtsdk4.NewVmBuilder().Id(vmID).MustBuild()).
+				Send()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Error adding VM to affinity label %s: %s", labelName, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *stepCreateVM) getProfiledTemplateDisks(connWrapper *ConnectionWrapper, config *Config, templateID, quotaID string) ([]*ovirtsdk4.DiskAttachment, error) {
+	var attachmentsResp *ovirtsdk4.TemplateDiskAttachmentsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		attachmentsResp, err = conn.SystemService().
+			TemplatesService().
+			TemplateService(templateID).
+			DiskAttachmentsService().
+			List().
+			Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting template disk attachments: %s", err)
+	}
+
+	var diskAttachments []*ovirtsdk4.DiskAttachment
+	if attachments, ok := attachmentsResp.Attachments(); ok {
+		for _, attachment := range attachments.Slice() {
+			disk, err := buildProfiledDisk(connWrapper, config, attachment.MustDisk().MustId(), quotaID)
+			if err != nil {
+				return nil, err
+			}
+			diskAttachments = append(diskAttachments, ovirtsdk4.NewDiskAttachmentBuilder().
+				Disk(disk).
+				MustBuild())
+		}
+	}
+
+	return diskAttachments, nil
+}
+
+func (s *stepCreateVM) getProfiledSnapshotDisks(connWrapper *ConnectionWrapper, config *Config, vmID, snapshotID, quotaID string) ([]*ovirtsdk4.DiskAttachment, error) {
+	var disksResp *ovirtsdk4.SnapshotDisksServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		disksResp, err = conn.SystemService().
+			VmsService().
+			VmService(vmID).
+			SnapshotsService().
+			SnapshotService(snapshotID).
+			DisksService().
+			List().
+			Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting snapshot disks: %s", err)
+	}
+
+	var diskAttachments []*ovirtsdk4.DiskAttachment
+	if disks, ok := disksResp.Disks(); ok {
+		for _, snapshotDisk := range disks.Slice() {
+			disk, err := buildProfiledDisk(connWrapper, config, snapshotDisk.MustId(), quotaID)
+			if err != nil {
+				return nil, err
+			}
+			diskAttachments = append(diskAttachments, ovirtsdk4.NewDiskAttachmentBuilder().
+				Disk(disk).
+				MustBuild())
+		}
+	}
+
+	return diskAttachments, nil
+}
+
+func (s *stepCreateVM) cloneDisk(connWrapper *ConnectionWrapper, config *Config, sourceDiskID, sourceDiskName, quotaID string) (string, error) {
 	// Generate unique name for cloned disk
 	epochTimestamp := strconv.FormatInt(time.Now().Unix(), 10)
 	clonedDiskName := fmt.Sprintf("%s-%s", sourceDiskName, epochTimestamp)
@@ -492,9 +1065,19 @@ func (s *stepCreateVM) cloneDisk(connWrapper *ConnectionWrapper, sourceDiskID, s
 	log.Printf("Source disk size: %d bytes, storage domain: %s", sourceDisk.MustProvisionedSize(), storageDomainID)
 
 	// Create the cloned disk object
-	clonedDisk, err := ovirtsdk4.NewDiskBuilder().
-		Name(clonedDiskName).
-		Build()
+	clonedDiskBuilder := ovirtsdk4.NewDiskBuilder().
+		Name(clonedDiskName)
+	if quotaID != "" {
+		clonedDiskBuilder.Quota(ovirtsdk4.NewQuotaBuilder().Id(quotaID).MustBuild())
+	}
+	if config.DiskProfile != "" {
+		diskProfileID, err := findDiskProfileID(connWrapper, storageDomainID, config.DiskProfile)
+		if err != nil {
+			return "", err
+		}
+		clonedDiskBuilder.DiskProfile(ovirtsdk4.NewDiskProfileBuilder().Id(diskProfileID).MustBuild())
+	}
+	clonedDisk, err := clonedDiskBuilder.Build()
 	if err != nil {
 		return "", fmt.Errorf("Error creating cloned disk object: %s", err)
 	}
@@ -717,8 +1300,8 @@ func (s *stepCreateVM) manageNetworkInterfaces(connWrapper *ConnectionWrapper, c
 		existingNics = nil
 	}
 
-	// For template-based VMs, check if there are existing network interfaces
-	if config.SourceConfig.GetSourceType() == "template" && existingNics != nil && len(existingNics.Slice()) > 0 {
+	// For template and VM based VMs, check if there are existing network interfaces
+	if config.SourceConfig.GetSourceType() != "disk" && existingNics != nil && len(existingNics.Slice()) > 0 {
 		log.Printf("Template has %d existing network interfaces", len(existingNics.Slice()))
 
 		// Use the first existing network interface and configure it
@@ -797,6 +1380,18 @@ func (s *stepCreateVM) manageNetworkInterfaces(connWrapper *ConnectionWrapper, c
 	return nil
 }
 
+// removeTemporarySnapshot removes the temporary snapshot of the source VM, if any
+func (s *stepCreateVM) removeTemporarySnapshot(connWrapper *ConnectionWrapper, ui packer.Ui, state multistep.StateBag) {
+	if s.temporarySnapshotID == "" {
+		return
+	}
+	ui.Message("Removing temporary snapshot of the source VM...")
+	if err := removeVMSnapshot(connWrapper, ui, state, s.temporarySnapshotVMID, s.temporarySnapshotID); err != nil {
+		ui.Error(fmt.Sprintf("Warning: %s", err))
+	}
+	s.temporarySnapshotID = ""
+}
+
 func (s *stepCreateVM) waitForVMReady(connWrapper *ConnectionWrapper, vmID string, state multistep.StateBag) error {
 	vmStateChange := StateChangeConf{
 		Pending:   []string{"image_locked"},
@@ -815,6 +1410,8 @@ func (s *stepCreateVM) Cleanup(state multistep.StateBag) {
 	ui := state.Get("ui").(packer.Ui)
 	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
 
+	s.removeTemporarySnapshot(connWrapper, ui, state)
+
 	vmID, ok := state.GetOk("vm_id")
 	if !ok {
 		return
@@ -839,6 +1436,24 @@ func (s *stepCreateVM) Cleanup(state multistep.StateBag) {
 	vm := vmResp.MustVm()
 	vmStatus := string(vm.MustStatus())
 
+	// A VM failing right after creation, e.g. on vm_tags, may still be copying
+	// its disks, and a locked VM can neither be stopped nor removed. The wait
+	// is not tied to the step state so it also runs when the build is cancelled.
+	if vmStatus == string(ovirtsdk4.VMSTATUS_IMAGE_LOCKED) {
+		ui.Say(fmt.Sprintf("Waiting for the disks of VM '%s' to be unlocked...", config.VMName))
+		lockStateChange := StateChangeConf{
+			Pending: []string{string(ovirtsdk4.VMSTATUS_IMAGE_LOCKED)},
+			Target:  []string{string(ovirtsdk4.VMSTATUS_DOWN)},
+			Refresh: VMStateRefreshFuncWithWrapper(connWrapper, vmID.(string)),
+			Timeout: config.JobTimeout,
+		}
+		if _, err := WaitForState(&lockStateChange); err != nil {
+			ui.Error(fmt.Sprintf("Error waiting for VM disks to be unlocked: %s", err))
+			return
+		}
+		vmStatus = string(ovirtsdk4.VMSTATUS_DOWN)
+	}
+
 	if vmStatus == string(ovirtsdk4.VMSTATUS_DOWN) {
 		ui.Say(fmt.Sprintf("VM '%s' is already stopped", config.VMName))
 	} else {
@@ -0,0 +1,216 @@
+package olvm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+type stepDownloadOVA struct{}
+
+func (s *stepDownloadOVA) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+
+	// Skip if no ova_download block is configured
+	download := config.OVADownload
+	if download == nil {
+		return multistep.ActionContinue
+	}
+
+	exports := state.Get("ova_exports").([]ovaExport)
+	ui.Say(fmt.Sprintf("Downloading %d OVA file(s) to %s...", len(exports), download.OutputDirectory))
+
+	if download.InsecureIgnoreHostKey {
+		ui.Say("Warning: insecure_ignore_host_key is set, the host keys of the export hosts are not verified")
+	}
+	clientConfig, err := s.clientConfig(download)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	var files []string
+	checksums := make(map[string]string)
+	for _, export := range exports {
+		// Keep OVAs with the same file name from different hosts apart
+		localPath := filepath.Join(download.OutputDirectory, path.Base(export.Path))
+		if len(exports) > 1 {
+			localPath = filepath.Join(download.OutputDirectory, export.Host, path.Base(export.Path))
+		}
+
+		// The engine host name is not necessarily resolvable, so connect to
+		// the address the engine manages the host by
+		address, err := s.hostAddress(connWrapper, export.Host)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+
+		ui.Message(fmt.Sprintf("Downloading %s:%s (%s) to %s...", export.Host, export.Path, address, localPath))
+		size, checksum, err := s.download(clientConfig, net.JoinHostPort(address, strconv.Itoa(download.Port)), export.Path, localPath)
+		if err != nil {
+			err = fmt.Errorf("Error downloading OVA %s from %s: %s", export.Path, export.Host, err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		ui.Message(fmt.Sprintf("Downloaded %s (%d bytes, SHA-256: %s)", localPath, size, checksum))
+
+		// Write a checksum file in the format of sha256sum
+		checksumPath := localPath + ".sha256"
+		checksumLine := fmt.Sprintf("%s  %s\n", checksum, filepath.Base(localPath))
+		if err := os.WriteFile(checksumPath, []byte(checksumLine), 0644); err != nil {
+			err = fmt.Errorf("Error writing checksum file %s: %s", checksumPath, err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+
+		files = append(files, localPath, checksumPath)
+		checksums[localPath] = checksum
+	}
+
+	state.Put("ova_download_files", files)
+	putArtifactState(state, "ova_download_sha256", checksums)
+
+	ui.Say("Successfully downloaded OVA files")
+	return multistep.ActionContinue
+}
+
+// clientConfig returns the SSH client configuration for the OVA download
+func (s *stepDownloadOVA) clientConfig(download *OVADownloadConfig) (*ssh.ClientConfig, error) {
+	var auth []ssh.AuthMethod
+	if download.PrivateKeyFile != "" {
+		privateKey, err := os.ReadFile(download.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading private key file: %s", err)
+		}
+		signer, err := ssh.ParsePrivateKey(privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing private key file: %s", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if download.Password != "" {
+		auth = append(auth, ssh.Password(download.Password))
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !download.InsecureIgnoreHostKey {
+		var err error
+		hostKeyCallback, err = knownhosts.New(download.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading known hosts file: %s", err)
+		}
+	}
+
+	return &ssh.ClientConfig{
+		User:            download.Username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         30 * time.Second,
+	}, nil
+}
+
+// hostAddress returns the address of the named host
+func (s *stepDownloadOVA) hostAddress(connWrapper *ConnectionWrapper, hostName string) (string, error) {
+	var hostsResp *ovirtsdk4.HostsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		hostsResp, err = conn.SystemService().HostsService().List().
+			Search(fmt.Sprintf("name=%s", hostName)).
+			Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error searching for host %s: %s", hostName, err)
+	}
+
+	if hosts, ok := hostsResp.Hosts(); ok {
+		for _, host := range hosts.Slice() {
+			if name, ok := host.Name(); ok && name == hostName {
+				address, ok := host.Address()
+				if !ok || address == "" {
+					return "", fmt.Errorf("Host %s has no address", hostName)
+				}
+				log.Printf("Using address %s for host %s", address, hostName)
+				return address, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("Host %s not found in OLVM", hostName)
+}
+
+// download copies the remote file over SFTP to the local path, verifies its
+// size and returns the size and SHA-256 checksum
+func (s *stepDownloadOVA) download(clientConfig *ssh.ClientConfig, address, remotePath, localPath string) (int64, string, error) {
+	client, err := ssh.Dial("tcp", address, clientConfig)
+	if err != nil {
+		return 0, "", fmt.Errorf("Error connecting over SSH: %s", err)
+	}
+	defer client.Close()
+
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		return 0, "", fmt.Errorf("Error starting SFTP session: %s", err)
+	}
+	defer sftpClient.Close()
+
+	remoteInfo, err := sftpClient.Stat(remotePath)
+	if err != nil {
+		return 0, "", fmt.Errorf("Error checking remote file: %s", err)
+	}
+	remote, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return 0, "", fmt.Errorf("Error opening remote file: %s", err)
+	}
+	defer remote.Close()
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return 0, "", fmt.Errorf("Error creating output directory: %s", err)
+	}
+	local, err := os.Create(localPath)
+	if err != nil {
+		return 0, "", fmt.Errorf("Error creating local file: %s", err)
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(local, hash), remote)
+	if closeErr := local.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size != remoteInfo.Size() {
+		err = fmt.Errorf("size mismatch: downloaded %d bytes, remote file has %d bytes", size, remoteInfo.Size())
+	}
+	if err != nil {
+		os.Remove(localPath)
+		return 0, "", err
+	}
+
+	log.Printf("Downloaded %d bytes from %s:%s to %s", size, address, remotePath, localPath)
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (s *stepDownloadOVA) Cleanup(state multistep.StateBag) {
+	// Nothing to cleanup for this step
+}
@@ -0,0 +1,169 @@
+package olvm
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestStepDownloadOVA_download(t *testing.T) {
+	content := []byte("packer-plugin-olvm test OVA")
+	remotePath := filepath.Join(t.TempDir(), "template.ova")
+	if err := os.WriteFile(remotePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	address, hostKey := startSFTPServer(t, "ovaexport", "secret")
+	s := &stepDownloadOVA{}
+
+	// The download succeeds against a known host key
+	knownHostsFile := writeKnownHosts(t, address, hostKey)
+	clientConfig, err := s.clientConfig(&OVADownloadConfig{
+		Username:       "ovaexport",
+		Password:       "secret",
+		KnownHostsFile: knownHostsFile,
+	})
+	if err != nil {
+		t.Fatalf("clientConfig: %s", err)
+	}
+
+	localPath := filepath.Join(t.TempDir(), "host1", "template.ova")
+	size, checksum, err := s.download(clientConfig, address, remotePath, localPath)
+	if err != nil {
+		t.Fatalf("download: %s", err)
+	}
+	if size != int64(len(content)) {
+		t.Errorf("size = %d, want %d", size, len(content))
+	}
+	sum := sha256.Sum256(content)
+	if want := hex.EncodeToString(sum[:]); checksum != want {
+		t.Errorf("checksum = %s, want %s", checksum, want)
+	}
+	downloaded, err := os.ReadFile(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(downloaded) != string(content) {
+		t.Errorf("downloaded content = %q, want %q", downloaded, content)
+	}
+
+	// The download fails against an unknown host key
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigner, err := ssh.NewSignerFromKey(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConfig, err = s.clientConfig(&OVADownloadConfig{
+		Username:       "ovaexport",
+		Password:       "secret",
+		KnownHostsFile: writeKnownHosts(t, address, otherSigner.PublicKey()),
+	})
+	if err != nil {
+		t.Fatalf("clientConfig: %s", err)
+	}
+	localPath = filepath.Join(t.TempDir(), "template.ova")
+	if _, _, err := s.download(clientConfig, address, remotePath, localPath); err == nil {
+		t.Fatal("download with an unknown host key succeeded")
+	}
+	if _, err := os.Stat(localPath); !os.IsNotExist(err) {
+		t.Errorf("local file exists after a failed download: %v", err)
+	}
+}
+
+// startSFTPServer starts an in-process SSH server with the sftp subsystem
+// which accepts the given password, returning its address and host key
+func startSFTPServer(t *testing.T, user, password string) (string, ssh.PublicKey) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == user && string(pass) == password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("password rejected for %s", c.User())
+		},
+	}
+	serverConfig.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, serverConfig)
+		}
+	}()
+
+	return listener.Addr().String(), signer.PublicKey()
+}
+
+// serveSFTP serves the sftp subsystem on the session channels of the connection
+func serveSFTP(conn net.Conn, serverConfig *ssh.ServerConfig) {
+	defer conn.Close()
+
+	_, channels, requests, err := ssh.NewServerConn(conn, serverConfig)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func(in <-chan *ssh.Request) {
+			for req := range in {
+				req.Reply(req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp", nil)
+			}
+		}(channelRequests)
+
+		server, err := sftp.NewServer(channel)
+		if err != nil {
+			return
+		}
+		server.Serve()
+		server.Close()
+	}
+}
+
+// writeKnownHosts writes a known hosts file with the host key for the address
+func writeKnownHosts(t *testing.T, address string, hostKey ssh.PublicKey) string {
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{address}, hostKey) + "\n"
+	if err := os.WriteFile(knownHostsFile, []byte(line), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return knownHostsFile
+}
@@ -0,0 +1,93 @@
+package olvm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+type stepExportTemplateToDomain struct{}
+
+func (s *stepExportTemplateToDomain) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+	templateID := state.Get("template_id").(string)
+
+	// Skip if export_storage_domain is not set
+	if config.ExportStorageDomain == "" {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Exporting template to export storage domain '%s'...", config.ExportStorageDomain))
+
+	storageDomainID, err := findStorageDomainID(connWrapper, config.ExportStorageDomain)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	clusterID, err := findClusterID(connWrapper, config.Cluster)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	dataCenterID, err := findClusterDataCenterID(connWrapper, clusterID)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	// The export domain must be active in the data center of the template
+	ui.Message("Attaching export storage domain to the template data center...")
+	canDetach := func(otherDataCenterID string) error {
+		if config.ExportStorageDomainDetach {
+			return nil
+		}
+		return fmt.Errorf("Export storage domain '%s' is attached to data center %s, set export_storage_domain_detach to detach it", config.ExportStorageDomain, otherDataCenterID)
+	}
+	if err := attachExportDomain(connWrapper, state, storageDomainID, dataCenterID, canDetach); err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	// The export is followed through its engine job, which reports failures
+	// the template status does not show
+	ui.Message(fmt.Sprintf("Exporting template %s (overwrite: %t)...", templateID, config.ExportStorageDomainOverwrite))
+	if err := exportTemplateToDomain(connWrapper, ui, state, templateID, storageDomainID, config.ExportStorageDomainOverwrite); err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	// The export job has finished, confirm the export domain holds the
+	// template under the expected ID
+	exported, err := findExportedTemplate(connWrapper, storageDomainID, templateID)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	if exported == nil {
+		err := fmt.Errorf("Template %s was not found on export storage domain '%s' after export", templateID, config.ExportStorageDomain)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	putArtifactState(state, "export_storage_domain", config.ExportStorageDomain)
+	putArtifactState(state, "export_storage_domain_template_id", exported.MustId())
+
+	ui.Say(fmt.Sprintf("Successfully exported template to export storage domain '%s'", config.ExportStorageDomain))
+	return multistep.ActionContinue
+}
+
+func (s *stepExportTemplateToDomain) Cleanup(state multistep.StateBag) {
+	// Nothing to cleanup for this step
+}
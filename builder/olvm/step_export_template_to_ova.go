@@ -1,17 +1,17 @@
 package olvm
 
 import (
-	"bytes"
 	"context"
-	"crypto/tls"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"time"
+	"log"
+	"path"
+	"strings"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
 	ovirtsdk4 "github.com/ovirt/go-ovirt"
 )
 
@@ -19,15 +19,25 @@ type stepExportTemplateToOVA struct {
 	Debug bool
 }
 
-// ExportRequest represents the JSON payload for the export API call
-type ExportRequest struct {
-	Host      HostInfo `json:"host"`
-	Directory string   `json:"directory"`
-	Filename  string   `json:"filename"`
+// ovaDestination is a rendered ova_export block
+type ovaDestination struct {
+	Host      string
+	Directory string
+	Filename  string
 }
 
-type HostInfo struct {
-	Name string `json:"name"`
+// ovaTemplateData holds the build variables available when interpolating
+// ova_export settings
+type ovaTemplateData struct {
+	BuildName    string
+	TemplateName string
+	Host         string
+}
+
+// ovaExport is an OVA file exported to a host
+type ovaExport struct {
+	Host string
+	Path string
 }
 
 func (s *stepExportTemplateToOVA) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -35,8 +45,8 @@ func (s *stepExportTemplateToOVA) Run(ctx context.Context, state multistep.State
 	ui := state.Get("ui").(packer.Ui)
 	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
 
-	// Skip if export_host is not set
-	if config.ExportHost == "" {
+	// Skip if no OVA exports are configured
+	if len(config.OVAExports) == 0 {
 		return multistep.ActionContinue
 	}
 
@@ -46,181 +56,152 @@ func (s *stepExportTemplateToOVA) Run(ctx context.Context, state multistep.State
 		return multistep.ActionHalt
 	}
 
-	ui.Say(fmt.Sprintf("Exporting template to OVA format on host %s...", config.ExportHost))
+	ui.Say(fmt.Sprintf("Exporting template to %d OVA destination(s)...", len(config.OVAExports)))
 
-	// Get the Templates service
-	var templatesService *ovirtsdk4.TemplatesService
-	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
-		templatesService = conn.SystemService().TemplatesService()
-		return nil
-	})
-	if err != nil {
-		err = fmt.Errorf("Error getting templates service: %s", err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
+	destinations := make([]ovaDestination, len(config.OVAExports))
+	for i, export := range config.OVAExports {
+		destination, err := s.renderExport(config, export)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		destinations[i] = destination
 	}
 
-	// Verify the template exists and is accessible
-	ui.Message(fmt.Sprintf("Verifying template %s exists and is accessible...", templateID.(string)))
-
-	var templateResp *ovirtsdk4.TemplateServiceGetResponse
-	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
-		var err error
-		templateResp, err = templatesService.TemplateService(templateID.(string)).Get().Send()
-		return err
-	})
-
-	if err != nil {
-		err = fmt.Errorf("Error accessing template %s: %s", templateID.(string), err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
+	// Run the exports one after another, since the engine locks the template
+	// while exporting it
+	var exports []ovaExport
+	var paths []string
+	for _, destination := range destinations {
+		ovaPath, err := s.exportTemplate(connWrapper, ui, state, templateID.(string), destination.Host, destination.Directory, destination.Filename)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		exports = append(exports, ovaExport{Host: destination.Host, Path: ovaPath})
+		paths = append(paths, fmt.Sprintf("%s:%s", destination.Host, ovaPath))
 	}
 
-	template := templateResp.MustTemplate()
+	state.Put("ova_exports", exports)
+	putArtifactState(state, "ova_exports", paths)
 
-	ui.Message(fmt.Sprintf("Template %s is accessible (Status: %s)", template.MustName(), template.MustStatus()))
+	ui.Say(fmt.Sprintf("Template export to OVA completed: %s", strings.Join(paths, ", ")))
+	return multistep.ActionContinue
+}
 
-	// Verify the host exists before attempting export
-	ui.Message(fmt.Sprintf("Verifying host %s exists...", config.ExportHost))
+// renderExport interpolates the ova_export settings with the build variables
+func (s *stepExportTemplateToOVA) renderExport(config *Config, export OVAExportConfig) (ovaDestination, error) {
+	ctx := config.ctx
+	data := &ovaTemplateData{
+		BuildName:    config.PackerBuildName,
+		TemplateName: config.DestinationTemplateName,
+	}
+	ctx.Data = data
 
-	var hostsService *ovirtsdk4.HostsService
-	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
-		hostsService = conn.SystemService().HostsService()
-		return nil
-	})
-	if err != nil {
-		err = fmt.Errorf("Error getting hosts service: %s", err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
+	var err error
+	var destination ovaDestination
+	if data.Host, err = interpolate.Render(export.Host, &ctx); err != nil {
+		return destination, fmt.Errorf("Error interpolating ova_export host '%s': %s", export.Host, err)
+	}
+	destination.Host = data.Host
+	if destination.Directory, err = interpolate.Render(export.Directory, &ctx); err != nil {
+		return destination, fmt.Errorf("Error interpolating ova_export directory '%s': %s", export.Directory, err)
 	}
+	if destination.Filename, err = interpolate.Render(export.FileName, &ctx); err != nil {
+		return destination, fmt.Errorf("Error interpolating ova_export file_name '%s': %s", export.FileName, err)
+	}
+	return destination, nil
+}
 
+// exportTemplate exports the template to an OVA on the host, tracking the
+// engine job until it completes, and returns the path of the OVA file
+func (s *stepExportTemplateToOVA) exportTemplate(connWrapper *ConnectionWrapper, ui packer.Ui, state multistep.StateBag, templateID, hostName, directory, filename string) (string, error) {
+	ovaPath := path.Join(directory, filename)
+
+	// Verify the host exists before attempting export
 	var hostsResp *ovirtsdk4.HostsServiceListResponse
-	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
 		var err error
-		hostsResp, err = hostsService.List().Search(fmt.Sprintf("name=%s", config.ExportHost)).Send()
+		hostsResp, err = conn.SystemService().HostsService().List().
+			Search(fmt.Sprintf("name=%s", hostName)).
+			Send()
 		return err
 	})
-
 	if err != nil {
-		err = fmt.Errorf("Error searching for host %s: %s", config.ExportHost, err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
+		return "", fmt.Errorf("Error searching for host %s: %s", hostName, err)
 	}
-
 	hosts, ok := hostsResp.Hosts()
 	if !ok || len(hosts.Slice()) == 0 {
-		err = fmt.Errorf("Host %s not found in OLVM", config.ExportHost)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
+		return "", fmt.Errorf("Host %s not found in OLVM", hostName)
 	}
-
 	host := hosts.Slice()[0]
-	ui.Message(fmt.Sprintf("Found host %s (ID: %s, Status: %s)",
-		host.MustName(), host.MustId(), host.MustStatus()))
+	ui.Message(fmt.Sprintf("Found host %s (ID: %s, Status: %s)", host.MustName(), host.MustId(), host.MustStatus()))
 
-	// Make direct HTTP API call to export the template
-	ui.Message("Calling OLVM API to export template...")
+	// The correlation ID ties the engine job and events to this export
+	correlationID := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	log.Printf("Exporting template %s to %s on host %s (correlation ID: %s)", templateID, ovaPath, hostName, correlationID)
 
-	// Build the export request payload
-	exportReq := ExportRequest{
-		Host: HostInfo{
-			Name: host.MustName(),
-		},
-		Directory: config.ExportDirectory,
-		Filename:  config.ExportFileName,
-	}
-
-	// Convert to JSON
-	jsonData, err := json.Marshal(exportReq)
+	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		_, err := conn.SystemService().
+			TemplatesService().
+			TemplateService(templateID).
+			ExportToPathOnHost().
+			Header("Correlation-Id", correlationID).
+			Host(ovirtsdk4.NewHostBuilder().Id(host.MustId()).MustBuild()).
+			Directory(directory).
+			Filename(filename).
+			Send()
+		return err
+	})
 	if err != nil {
-		err = fmt.Errorf("Error marshaling export request: %s", err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
+		return "", fmt.Errorf("Error exporting template to OVA %s on host %s: %s", ovaPath, hostName, err)
 	}
+	ui.Message(fmt.Sprintf("Started export to %s on host %s", ovaPath, hostName))
 
-	// Build the API URL
-	apiURL := fmt.Sprintf("%s/templates/%s/export",
-		config.AccessConfig.olvmParsedURL.String(), templateID.(string))
-
-	// Create HTTP client with TLS skip if configured
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: config.AccessConfig.TLSInsecure,
-			},
-		},
+	// Follow the engine job, reporting its steps as they progress
+	if err := waitForJob(connWrapper, ui, state, fmt.Sprintf("Export job step on %s", hostName), correlationID); err != nil {
+		return "", fmt.Errorf("Error waiting for OVA export job: %s", err)
 	}
 
-	// Create the HTTP request
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		err = fmt.Errorf("Error creating HTTP request: %s", err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
+	// A finished job does not guarantee the OVA was written, so confirm it
+	// with the engine events of the export. The engine cannot list the files
+	// on the host, so the OVA itself is only checked when it is downloaded.
+	if err := s.verifyExport(connWrapper, correlationID, filename); err != nil {
+		return "", err
 	}
+	ui.Message(fmt.Sprintf("Engine confirmed OVA %s on host %s", ovaPath, hostName))
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Basic %s", config.AccessConfig.getBasicAuth()))
-
-	// Send the request
-	resp, err := client.Do(req)
-	if err != nil {
-		err = fmt.Errorf("Error making HTTP request: %s", err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
-	}
-	defer resp.Body.Close()
+	return ovaPath, nil
+}
 
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
+// verifyExport checks the engine events of the export for errors and for the
+// event reporting the OVA file
+func (s *stepExportTemplateToOVA) verifyExport(connWrapper *ConnectionWrapper, correlationID, filename string) error {
+	events, err := findEvents(connWrapper, correlationID)
 	if err != nil {
-		err = fmt.Errorf("Error reading response body: %s", err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
-	}
-
-	// Check response status
-	if resp.StatusCode >= 400 {
-		err = fmt.Errorf("Export API call failed with status %s: %s", resp.Status, string(body))
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
-	}
-
-	ui.Say(fmt.Sprintf("Successfully initiated template export to OVA: %s/%s on host %s",
-		config.ExportDirectory, config.ExportFileName, config.ExportHost))
-
-	// Poll for export completion (template status returns to 'ok')
-	ui.Message("Waiting for template export to complete (status returns to 'ok')...")
-	templateStateChange := StateChangeConf{
-		Pending:   []string{"locked", "image_locked"},
-		Target:    []string{"ok"},
-		Refresh:   TemplateStateRefreshFuncWithWrapper(connWrapper, templateID.(string)),
-		StepState: state,
-	}
-	if _, err := WaitForState(&templateStateChange); err != nil {
-		err := fmt.Errorf("Error waiting for template export to complete: %s", err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
+		return err
 	}
 
-	ui.Say(fmt.Sprintf("Template export to OVA completed: %s/%s on host %s",
-		config.ExportDirectory, config.ExportFileName, config.ExportHost))
-
-	return multistep.ActionContinue
+	confirmed := false
+	for _, event := range events {
+		description, _ := event.Description()
+		severity, _ := event.Severity()
+		log.Printf("Export event (%s): %s", severity, description)
+		switch severity {
+		case ovirtsdk4.LOGSEVERITY_ERROR, ovirtsdk4.LOGSEVERITY_ALERT:
+			return fmt.Errorf("OVA export failed: %s", description)
+		case ovirtsdk4.LOGSEVERITY_NORMAL:
+			if strings.Contains(description, filename) {
+				confirmed = true
+			}
+		}
+	}
+	if !confirmed {
+		return errors.New("OVA export finished, but the engine did not report the exported OVA file")
+	}
+	return nil
 }
 
 func (s *stepExportTemplateToOVA) Cleanup(state multistep.StateBag) {
@@ -0,0 +1,89 @@
+package olvm
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// generalizeOperations lists the supported guest generalization operations
+// in their default execution order, with the shell command run for each
+var generalizeOperations = []struct {
+	Name    string
+	Command string
+}{
+	{"machine_id", "truncate -s 0 /etc/machine-id && rm -f /var/lib/dbus/machine-id"},
+	{"ssh_host_keys", "rm -f /etc/ssh/ssh_host_*"},
+	{"cloud_init", "if command -v cloud-init >/dev/null 2>&1; then cloud-init clean --logs; else rm -rf /var/lib/cloud/instances /var/lib/cloud/instance; fi"},
+	{"logs", "find /var/log -type f -exec truncate -s 0 {} + && if command -v journalctl >/dev/null 2>&1; then journalctl --rotate && journalctl --vacuum-time=1s; fi"},
+	{"shell_history", "rm -f /root/.bash_history /home/*/.bash_history"},
+	{"dhcp_leases", "rm -f /var/lib/dhclient/* /var/lib/dhcp/*.leases /var/lib/NetworkManager/*.lease"},
+	// fstrim fails on guests without discard support, which is not fatal
+	{"fstrim", "fstrim -av || true"},
+}
+
+type stepGeneralizeGuest struct{}
+
+func (s *stepGeneralizeGuest) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	// Skip if generalize is not set
+	if !config.Generalize {
+		return multistep.ActionContinue
+	}
+
+	comm, ok := state.GetOk("communicator")
+	if !ok {
+		err := fmt.Errorf("No communicator available to generalize the guest")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Generalizing guest operating system...")
+
+	for _, operation := range config.GeneralizeOperations {
+		ui.Message(fmt.Sprintf("Generalize: %s", operation))
+
+		command := fmt.Sprintf("sh -c '%s'", generalizeCommand(operation))
+		if config.Comm.SSHUsername != "root" {
+			command = fmt.Sprintf("sudo -n %s", command)
+		}
+		log.Printf("Executing generalize command: %s", command)
+
+		cmd := &packer.RemoteCmd{Command: command}
+		if err := cmd.RunWithUi(ctx, comm.(packer.Communicator), ui); err != nil {
+			err = fmt.Errorf("Error running generalize operation %s: %s", operation, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if cmd.ExitStatus() != 0 {
+			err := fmt.Errorf("Generalize operation %s failed with exit status %d", operation, cmd.ExitStatus())
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	ui.Say("Successfully generalized guest operating system")
+	return multistep.ActionContinue
+}
+
+// generalizeCommand returns the shell command for the named generalize operation
+func generalizeCommand(operation string) string {
+	for _, op := range generalizeOperations {
+		if op.Name == operation {
+			return op.Command
+		}
+	}
+	return ""
+}
+
+func (s *stepGeneralizeGuest) Cleanup(state multistep.StateBag) {
+	// Nothing to cleanup for this step
+}
@@ -0,0 +1,165 @@
+package olvm
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// stepImportOVA imports the source OVA as the build VM. The import already
+// copies the disks, so the VM is built from directly instead of being cloned.
+type stepImportOVA struct {
+	importStarted bool
+}
+
+func (s *stepImportOVA) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+
+	// Skip if the source is not an OVA
+	if config.SourceConfig.GetSourceType() != "ova" {
+		return multistep.ActionContinue
+	}
+	sourceOVA := config.SourceOVA
+
+	ui.Say(fmt.Sprintf("Importing OVA %s from host %s...", sourceOVA.Path, sourceOVA.Host))
+
+	clusterID, err := findClusterID(connWrapper, config.Cluster)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	storageDomainID, err := findStorageDomainID(connWrapper, sourceOVA.StorageDomain)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	ovaImport, err := ovirtsdk4.NewExternalVmImportBuilder().
+		Provider(ovirtsdk4.EXTERNALVMPROVIDERTYPE_KVM).
+		Url(fmt.Sprintf("ova://%s", sourceOVA.Path)).
+		Host(ovirtsdk4.NewHostBuilder().Name(sourceOVA.Host).MustBuild()).
+		Cluster(ovirtsdk4.NewClusterBuilder().Id(clusterID).MustBuild()).
+		StorageDomain(ovirtsdk4.NewStorageDomainBuilder().Id(storageDomainID).MustBuild()).
+		Vm(ovirtsdk4.NewVmBuilder().Name(config.VMName).MustBuild()).
+		Sparse(sourceOVA.Sparse).
+		Build()
+	if err != nil {
+		err = fmt.Errorf("Error creating OVA import object: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	// The correlation ID ties the engine job and events to this import
+	correlationID := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	log.Printf("Importing OVA %s on host %s as VM %s (correlation ID: %s)", sourceOVA.Path, sourceOVA.Host, config.VMName, correlationID)
+
+	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		_, err := conn.SystemService().
+			ExternalVmImportsService().
+			Add().
+			Header("Correlation-Id", correlationID).
+			Import(ovaImport).
+			Send()
+		return err
+	})
+	if err != nil {
+		err = fmt.Errorf("Error importing OVA %s from host %s: %s", sourceOVA.Path, sourceOVA.Host, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	s.importStarted = true
+	ui.Message(fmt.Sprintf("Started import of VM '%s'", config.VMName))
+
+	// Follow the engine job, reporting its steps as they progress
+	if err := waitForJob(connWrapper, ui, state, "Import job step", correlationID); err != nil {
+		err = fmt.Errorf("Error waiting for OVA import job: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	vm, err := findVM(connWrapper, config.VMName, "")
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	vmID := vm.MustId()
+	state.Put("ova_vm_id", vmID)
+
+	// Wait for the imported disks to be unlocked, which ends the import as
+	// much as the job does
+	vmStateChange := StateChangeConf{
+		Pending:   []string{string(ovirtsdk4.VMSTATUS_IMAGE_LOCKED)},
+		Target:    []string{string(ovirtsdk4.VMSTATUS_DOWN)},
+		Refresh:   VMStateRefreshFuncWithWrapper(connWrapper, vmID),
+		StepState: state,
+		Timeout:   config.JobTimeout,
+	}
+	if _, err := WaitForState(&vmStateChange); err != nil {
+		err = fmt.Errorf("Error waiting for imported VM to be ready: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	ui.Message(fmt.Sprintf("Imported OVA as VM '%s' (ID: %s)", config.VMName, vmID))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepImportOVA) Cleanup(state multistep.StateBag) {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+
+	// Once stepCreateVM takes the imported VM over, it also cleans it up
+	if !s.importStarted {
+		return
+	}
+	if _, ok := state.GetOk("vm_id"); ok {
+		return
+	}
+
+	if !*config.CleanupVM {
+		ui.Say(fmt.Sprintf("Skipping imported VM cleanup. VM '%s' will remain in the system.", config.VMName))
+		return
+	}
+
+	// The VM ID is only known once the import finished, look a partially
+	// imported VM up by name
+	var vmID string
+	if rawVMID, ok := state.GetOk("ova_vm_id"); ok {
+		vmID = rawVMID.(string)
+	} else {
+		vm, err := findVM(connWrapper, config.VMName, "")
+		if err != nil {
+			log.Printf("No imported VM to clean up: %s", err)
+			return
+		}
+		vmID = vm.MustId()
+	}
+
+	ui.Say(fmt.Sprintf("Deleting imported VM: %s", config.VMName))
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		_, err := conn.SystemService().
+			VmsService().
+			VmService(vmID).
+			Remove().
+			Send()
+		return err
+	})
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error deleting imported VM: %s", err))
+	}
+}
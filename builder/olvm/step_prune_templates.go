@@ -0,0 +1,271 @@
+package olvm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepPruneTemplates struct{}
+
+func (s *stepPruneTemplates) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+	templateID := state.Get("template_id").(string)
+
+	// Skip if no template_retention block is configured
+	retention := config.TemplateRetention
+	if retention == nil {
+		return multistep.ActionContinue
+	}
+
+	if retention.DryRun {
+		ui.Say("Checking template retention (dry run)...")
+	} else {
+		ui.Say("Applying template retention...")
+	}
+
+	templates, err := s.findRetentionTemplates(connWrapper, retention)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	// Replaced templates are removed by the next step, whatever the retention
+	if replaced, ok := state.GetOk("replaced_templates"); ok {
+		replacedIDs := make(map[string]bool)
+		for _, tp := range replaced.([]*ovirtsdk4.Template) {
+			replacedIDs[tp.MustId()] = true
+		}
+		var retained []*ovirtsdk4.Template
+		for _, tp := range templates {
+			if !replacedIDs[tp.MustId()] {
+				retained = append(retained, tp)
+			}
+		}
+		templates = retained
+	}
+
+	inUse, err := s.findTemplatesInUse(connWrapper)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	// Every template this build produced is kept
+	produced := map[string]bool{templateID: true}
+	if publishedIDs, ok := state.GetOk("published_template_ids"); ok {
+		for _, id := range publishedIDs.(map[string]string) {
+			produced[id] = true
+		}
+	}
+
+	// Templates are scoped to a data center, so keep_last applies per data center
+	byDataCenter, err := s.groupByDataCenter(connWrapper, templates)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	var kept, expired []*ovirtsdk4.Template
+	for dataCenter, dcTemplates := range byDataCenter {
+		// Newest templates first, so that keep_last keeps the most recent builds
+		sort.SliceStable(dcTemplates, func(i, j int) bool {
+			return dcTemplates[i].MustCreationTime().After(dcTemplates[j].MustCreationTime())
+		})
+
+		dcKept := 0
+		for i, tp := range dcTemplates {
+			age := time.Since(tp.MustCreationTime())
+			if produced[tp.MustId()] || i < retention.KeepLast || (retention.MaxAge > 0 && age <= retention.MaxAge) {
+				kept = append(kept, tp)
+				dcKept++
+				continue
+			}
+			expired = append(expired, tp)
+		}
+		log.Printf("Template retention matched %d template(s) in data center %s, %d kept", len(dcTemplates), dataCenter, dcKept)
+	}
+	log.Printf("Template retention matched %d template(s), %d kept, %d expired", len(kept)+len(expired), len(kept), len(expired))
+
+	// A base template cannot be removed while any of its sub-versions remain
+	keptBaseIDs := make(map[string]bool)
+	for _, tp := range kept {
+		if baseID := templateBaseID(tp); baseID != "" {
+			keptBaseIDs[baseID] = true
+		}
+	}
+
+	// Remove sub-versions before their base templates
+	sort.SliceStable(expired, func(i, j int) bool {
+		return templateVersionNumber(expired[i]) > templateVersionNumber(expired[j])
+	})
+
+	var removed []string
+	for _, tp := range expired {
+		tpID := tp.MustId()
+		label := fmt.Sprintf("'%s' version %d (ID: %s, created %s)", tp.MustName(), templateVersionNumber(tp), tpID, tp.MustCreationTime().Format(time.RFC3339))
+
+		if inUse[tpID] {
+			ui.Message(fmt.Sprintf("Skipping template %s: still used by VMs or pools", label))
+			continue
+		}
+		if deleteProtected, ok := tp.DeleteProtected(); ok && deleteProtected {
+			ui.Message(fmt.Sprintf("Skipping template %s: delete protected", label))
+			continue
+		}
+		if keptBaseIDs[tpID] {
+			ui.Message(fmt.Sprintf("Skipping template %s: has sub-versions that are kept", label))
+			continue
+		}
+
+		if retention.DryRun {
+			ui.Message(fmt.Sprintf("Would remove template %s", label))
+			removed = append(removed, tpID)
+			continue
+		}
+
+		ui.Message(fmt.Sprintf("Removing template %s...", label))
+		if err := removeTemplate(connWrapper, state, tpID); err != nil {
+			// The build itself succeeded, so a failed removal is only reported
+			ui.Error(fmt.Sprintf("Warning: %s", err))
+			continue
+		}
+		removed = append(removed, tpID)
+	}
+
+	if retention.DryRun {
+		putArtifactState(state, "retention_dry_run_templates", removed)
+		ui.Say(fmt.Sprintf("Template retention dry run: %d template(s) would be removed", len(removed)))
+	} else {
+		putArtifactState(state, "retention_removed_templates", removed)
+		ui.Say(fmt.Sprintf("Template retention removed %d template(s)", len(removed)))
+	}
+
+	return multistep.ActionContinue
+}
+
+// findRetentionTemplates returns all templates matching the retention name
+// prefix and tag
+func (s *stepPruneTemplates) findRetentionTemplates(connWrapper *ConnectionWrapper, retention *TemplateRetentionConfig) ([]*ovirtsdk4.Template, error) {
+	var criteria []string
+	if retention.NamePrefix != "" {
+		criteria = append(criteria, fmt.Sprintf("name=%s*", retention.NamePrefix))
+	}
+	if retention.Tag != "" {
+		criteria = append(criteria, fmt.Sprintf("tag=%s", retention.Tag))
+	}
+	search := strings.Join(criteria, " and ")
+	log.Printf("Searching templates for retention: %s", search)
+
+	var tpsResp *ovirtsdk4.TemplatesServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		tpsResp, err = conn.SystemService().TemplatesService().List().Search(search).Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error searching templates for retention: %s", err)
+	}
+
+	var templates []*ovirtsdk4.Template
+	if tpSlice, ok := tpsResp.Templates(); ok {
+		for _, tp := range tpSlice.Slice() {
+			name, _ := tp.Name()
+			if name == "Blank" || !strings.HasPrefix(name, retention.NamePrefix) {
+				continue
+			}
+			if _, ok := tp.CreationTime(); !ok {
+				continue
+			}
+			templates = append(templates, tp)
+		}
+	}
+	return templates, nil
+}
+
+// groupByDataCenter groups the templates by the name of the data center of
+// their cluster. Templates without a cluster, such as those only present on
+// export domains, are left out.
+func (s *stepPruneTemplates) groupByDataCenter(connWrapper *ConnectionWrapper, templates []*ovirtsdk4.Template) (map[string][]*ovirtsdk4.Template, error) {
+	dataCenterNames, err := findClusterDataCenterNames(connWrapper)
+	if err != nil {
+		return nil, err
+	}
+
+	byDataCenter := make(map[string][]*ovirtsdk4.Template)
+	for _, tp := range templates {
+		var dataCenter string
+		if cluster, ok := tp.Cluster(); ok {
+			if clusterID, ok := cluster.Id(); ok {
+				dataCenter = dataCenterNames[clusterID]
+			}
+		}
+		if dataCenter == "" {
+			log.Printf("Skipping template %s for retention: no data center", tp.MustId())
+			continue
+		}
+		byDataCenter[dataCenter] = append(byDataCenter[dataCenter], tp)
+	}
+	return byDataCenter, nil
+}
+
+// findTemplatesInUse returns the IDs of all templates referenced by VMs or VM pools
+func (s *stepPruneTemplates) findTemplatesInUse(connWrapper *ConnectionWrapper) (map[string]bool, error) {
+	inUse := make(map[string]bool)
+
+	var vmsResp *ovirtsdk4.VmsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		vmsResp, err = conn.SystemService().VmsService().List().Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing VMs: %s", err)
+	}
+	if vms, ok := vmsResp.Vms(); ok {
+		for _, vm := range vms.Slice() {
+			if tp, ok := vm.Template(); ok {
+				if id, ok := tp.Id(); ok {
+					inUse[id] = true
+				}
+			}
+		}
+	}
+
+	var poolsResp *ovirtsdk4.VmPoolsServiceListResponse
+	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		poolsResp, err = conn.SystemService().VmPoolsService().List().Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing VM pools: %s", err)
+	}
+	if pools, ok := poolsResp.Pools(); ok {
+		for _, pool := range pools.Slice() {
+			if tp, ok := pool.Template(); ok {
+				if id, ok := tp.Id(); ok {
+					inUse[id] = true
+				}
+			}
+		}
+	}
+
+	return inUse, nil
+}
+
+func (s *stepPruneTemplates) Cleanup(state multistep.StateBag) {
+	// Nothing to cleanup for this step
+}
@@ -0,0 +1,325 @@
+package olvm
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepPublishTemplate struct {
+	// Templates published to the target data centers, by data center name,
+	// removed again if the build fails
+	publishedIDs map[string]string
+
+	// Templates in the target data centers renamed out of the way by
+	// on_template_exists = "replace", by data center name. They are removed
+	// by stepRemoveReplacedTemplates once the build succeeded, or get their
+	// name back if it fails.
+	replacedTemplates map[string][]*ovirtsdk4.Template
+}
+
+func (s *stepPublishTemplate) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+	templateID := state.Get("template_id").(string)
+
+	// Skip if no publish targets are configured
+	if len(config.PublishTargets) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Publishing template to %d data center(s) through export domain '%s'...", len(config.PublishTargets), config.PublishExportDomain))
+
+	halt := func(err error) multistep.StepAction {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	exportDomainID, err := findStorageDomainID(connWrapper, config.PublishExportDomain)
+	if err != nil {
+		return halt(err)
+	}
+	clusterID, err := findClusterID(connWrapper, config.Cluster)
+	if err != nil {
+		return halt(err)
+	}
+	sourceDataCenterID, err := findClusterDataCenterID(connWrapper, clusterID)
+	if err != nil {
+		return halt(err)
+	}
+
+	// The export domain moves between the source and target data centers.
+	// Detaching it from any other data center must be allowed explicitly.
+	publishDataCenters := map[string]bool{sourceDataCenterID: true}
+	for _, target := range config.PublishTargets {
+		dataCenterID, err := findDataCenterID(connWrapper, target.DataCenter)
+		if err != nil {
+			return halt(err)
+		}
+		publishDataCenters[dataCenterID] = true
+	}
+	canDetach := func(dataCenterID string) error {
+		if config.PublishExportDomainDetach || publishDataCenters[dataCenterID] {
+			return nil
+		}
+		return fmt.Errorf("Export domain '%s' is attached to data center %s, set publish_export_domain_detach to detach it", config.PublishExportDomain, dataCenterID)
+	}
+
+	// Export the template from the source data center
+	ui.Message(fmt.Sprintf("Attaching export domain '%s' to the source data center...", config.PublishExportDomain))
+	if err := attachExportDomain(connWrapper, state, exportDomainID, sourceDataCenterID, canDetach); err != nil {
+		return halt(err)
+	}
+	ui.Message(fmt.Sprintf("Exporting template %s to export domain '%s'...", templateID, config.PublishExportDomain))
+	if err := exportTemplateToDomain(connWrapper, ui, state, templateID, exportDomainID, true); err != nil {
+		return halt(err)
+	}
+
+	// The published templates are recorded as they are imported, so that a
+	// failing target does not lose track of the earlier ones
+	s.publishedIDs = make(map[string]string)
+	s.replacedTemplates = make(map[string][]*ovirtsdk4.Template)
+	state.Put("published_template_ids", s.publishedIDs)
+	putArtifactState(state, "published_template_ids", s.publishedIDs)
+	for _, target := range config.PublishTargets {
+		ui.Message(fmt.Sprintf("Publishing template to data center '%s' (cluster '%s', storage domain '%s')...", target.DataCenter, target.Cluster, target.StorageDomain))
+
+		// Apply on_template_exists to the templates already in the target
+		existingTemplates, err := findClusterTemplatesByName(connWrapper, target.Cluster, config.DestinationTemplateName)
+		if err != nil {
+			return halt(err)
+		}
+		if len(existingTemplates) > 0 {
+			switch config.OnTemplateExists {
+			case "replace":
+				// Keep the existing template until the build succeeded
+				err = checkDeleteProtection(existingTemplates)
+				if err == nil {
+					err = renameBaseTemplate(connWrapper, ui, existingTemplates, timestampedName(config.DestinationTemplateName))
+				}
+				if err == nil {
+					s.replacedTemplates[target.DataCenter] = existingTemplates
+				}
+			case "rename":
+				err = renameBaseTemplate(connWrapper, ui, existingTemplates, timestampedName(config.DestinationTemplateName))
+			default:
+				err = fmt.Errorf("Template '%s' already exists in data center %s", config.DestinationTemplateName, target.DataCenter)
+			}
+			if err != nil {
+				return halt(err)
+			}
+		}
+
+		publishedID, err := s.importTemplate(connWrapper, state, config, exportDomainID, templateID, target, canDetach)
+		if publishedID != "" {
+			s.publishedIDs[target.DataCenter] = publishedID
+		}
+		if err != nil {
+			return halt(fmt.Errorf("Error publishing template to data center %s: %s", target.DataCenter, err))
+		}
+		ui.Message(fmt.Sprintf("Published template to data center '%s' with ID: %s", target.DataCenter, publishedID))
+
+		if len(config.TemplateTags) > 0 {
+			tags, err := renderTags(config, config.TemplateTags)
+			if err == nil {
+				err = assignTags(connWrapper, tags, func(conn *ovirtsdk4.Connection) *ovirtsdk4.AssignedTagsService {
+					return conn.SystemService().TemplatesService().TemplateService(publishedID).TagsService()
+				})
+			}
+			if err != nil {
+				return halt(err)
+			}
+		}
+		if permissions, ok := state.GetOk("template_permissions"); ok {
+			if err := assignTemplatePermissions(connWrapper, publishedID, permissions.([]templatePermission)); err != nil {
+				return halt(err)
+			}
+		}
+	}
+
+	// The replaced templates are only removed once the remaining steps
+	// succeeded, until then the build can still give them their name back
+	var replaced []*ovirtsdk4.Template
+	if rawReplaced, ok := state.GetOk("replaced_templates"); ok {
+		replaced = rawReplaced.([]*ovirtsdk4.Template)
+	}
+	for _, templates := range s.replacedTemplates {
+		replaced = append(replaced, templates...)
+	}
+	if len(replaced) > 0 {
+		state.Put("replaced_templates", replaced)
+	}
+
+	// Remove the transfer copy from the export domain
+	log.Printf("Removing template %s from export domain %s", templateID, exportDomainID)
+	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		_, err := conn.SystemService().
+			StorageDomainsService().
+			StorageDomainService(exportDomainID).
+			TemplatesService().
+			TemplateService(templateID).
+			Remove().
+			Send()
+		return err
+	})
+	if err != nil {
+		ui.Error(fmt.Sprintf("Warning: Error removing template %s from export domain: %s", templateID, err))
+	}
+
+	ui.Say("Successfully published template")
+	return multistep.ActionContinue
+}
+
+// importTemplate imports the exported template into the target data center as
+// a new template with the destination template name and returns its ID. The ID
+// is also returned when waiting for the imported template fails, so that the
+// template can be removed.
+func (s *stepPublishTemplate) importTemplate(connWrapper *ConnectionWrapper, state multistep.StateBag, config *Config, exportDomainID, templateID string, target PublishTargetConfig, canDetach func(dataCenterID string) error) (string, error) {
+	dataCenterID, err := findDataCenterID(connWrapper, target.DataCenter)
+	if err != nil {
+		return "", err
+	}
+	clusterID, err := findClusterID(connWrapper, target.Cluster)
+	if err != nil {
+		return "", err
+	}
+	storageDomainID, err := findStorageDomainID(connWrapper, target.StorageDomain)
+	if err != nil {
+		return "", err
+	}
+
+	if err := attachExportDomain(connWrapper, state, exportDomainID, dataCenterID, canDetach); err != nil {
+		return "", err
+	}
+
+	exported, err := findExportedTemplate(connWrapper, exportDomainID, templateID)
+	if err != nil {
+		return "", err
+	}
+	if exported == nil {
+		return "", fmt.Errorf("Template %s not found on export domain %s", templateID, config.PublishExportDomain)
+	}
+
+	// Remember existing templates so that the imported one can be told apart
+	search := fmt.Sprintf("name=%s and datacenter=%s", config.DestinationTemplateName, target.DataCenter)
+	existing, err := s.searchTemplateIDs(connWrapper, search)
+	if err != nil {
+		return "", err
+	}
+
+	// The template is cloned so that it gets new IDs in the target data center
+	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		_, err := conn.SystemService().
+			StorageDomainsService().
+			StorageDomainService(exportDomainID).
+			TemplatesService().
+			TemplateService(templateID).
+			Import().
+			Cluster(ovirtsdk4.NewClusterBuilder().Id(clusterID).MustBuild()).
+			StorageDomain(ovirtsdk4.NewStorageDomainBuilder().Id(storageDomainID).MustBuild()).
+			Template(ovirtsdk4.NewTemplateBuilder().Name(config.DestinationTemplateName).MustBuild()).
+			Clone(true).
+			Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error importing template: %s", err)
+	}
+
+	importStateChange := StateChangeConf{
+		Pending:   []string{"", "locked", "image_locked"},
+		Target:    []string{"ok"},
+		Refresh:   s.importedTemplateRefreshFunc(connWrapper, search, existing),
+		StepState: state,
+	}
+	result, err := WaitForState(&importStateChange)
+	if err != nil {
+		err = fmt.Errorf("Error waiting for template import: %s", err)
+		if ids, searchErr := s.searchTemplateIDs(connWrapper, search); searchErr == nil {
+			for id := range ids {
+				if !existing[id] {
+					return id, err
+				}
+			}
+		}
+		return "", err
+	}
+	return result.(*ovirtsdk4.Template).MustId(), nil
+}
+
+// searchTemplateIDs returns the IDs of the templates matching the search
+func (s *stepPublishTemplate) searchTemplateIDs(connWrapper *ConnectionWrapper, search string) (map[string]bool, error) {
+	var tpsResp *ovirtsdk4.TemplatesServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		tpsResp, err = conn.SystemService().TemplatesService().List().Search(search).Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error searching templates: %s", err)
+	}
+
+	ids := make(map[string]bool)
+	if templates, ok := tpsResp.Templates(); ok {
+		for _, tp := range templates.Slice() {
+			ids[tp.MustId()] = true
+		}
+	}
+	return ids, nil
+}
+
+// importedTemplateRefreshFunc returns a StateRefreshFunc that watches for a
+// template matching the search that is not one of the existing templates.
+// The state is empty until the engine reports the imported template.
+func (s *stepPublishTemplate) importedTemplateRefreshFunc(connWrapper *ConnectionWrapper, search string, existing map[string]bool) StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		var tpsResp *ovirtsdk4.TemplatesServiceListResponse
+		err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			var err error
+			tpsResp, err = conn.SystemService().TemplatesService().List().Search(search).Send()
+			return err
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		if templates, ok := tpsResp.Templates(); ok {
+			for _, tp := range templates.Slice() {
+				if !existing[tp.MustId()] {
+					return tp, string(tp.MustStatus()), nil
+				}
+			}
+		}
+		return nil, "", nil
+	}
+}
+
+func (s *stepPublishTemplate) Cleanup(state multistep.StateBag) {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+
+	if !buildFailed(state) {
+		return
+	}
+
+	// The published templates hold the name of the replaced templates, so
+	// they are removed before the replaced templates get their name back
+	for dataCenter, publishedID := range s.publishedIDs {
+		ui.Say(fmt.Sprintf("Removing template published to data center '%s'...", dataCenter))
+		discardTemplate(connWrapper, ui, config, publishedID)
+		delete(s.publishedIDs, dataCenter)
+	}
+	for dataCenter, templates := range s.replacedTemplates {
+		if err := renameBaseTemplate(connWrapper, ui, templates, config.DestinationTemplateName); err != nil {
+			ui.Error(err.Error())
+		}
+		delete(s.replacedTemplates, dataCenter)
+	}
+}
@@ -0,0 +1,36 @@
+package olvm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// stepRemoveReplacedTemplates removes the templates replaced by
+// on_template_exists = "replace" as the last step, so that a failing step
+// before it can still give them their name back
+type stepRemoveReplacedTemplates struct{}
+
+func (s *stepRemoveReplacedTemplates) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+
+	replaced, ok := state.GetOk("replaced_templates")
+	if !ok {
+		return multistep.ActionContinue
+	}
+
+	if err := removeTemplates(connWrapper, ui, state, replaced.([]*ovirtsdk4.Template)); err != nil {
+		// The build itself succeeded, so a failed removal is only reported
+		ui.Error(fmt.Sprintf("Warning: %s", err))
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepRemoveReplacedTemplates) Cleanup(state multistep.StateBag) {
+	// Nothing to cleanup for this step
+}
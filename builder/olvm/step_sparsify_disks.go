@@ -0,0 +1,149 @@
+package olvm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepSparsifyDisks struct{}
+
+func (s *stepSparsifyDisks) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+	vmID := state.Get("vm_id").(string)
+
+	// Skip if sparsify is not set
+	if !config.Sparsify {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Sparsifying VM disks...")
+
+	var attachmentsResp *ovirtsdk4.DiskAttachmentsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		attachmentsResp, err = conn.SystemService().
+			VmsService().
+			VmService(vmID).
+			DiskAttachmentsService().
+			List().
+			Send()
+		return err
+	})
+	if err != nil {
+		err = fmt.Errorf("Error getting VM disk attachments: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	attachments, ok := attachmentsResp.Attachments()
+	if !ok || len(attachments.Slice()) == 0 {
+		ui.Say("No disks found on VM")
+		return multistep.ActionContinue
+	}
+
+	sizesBefore := make(map[string]string)
+	sizesAfter := make(map[string]string)
+	for _, attachment := range attachments.Slice() {
+		diskID := attachment.MustDisk().MustId()
+
+		disk, err := s.getDisk(connWrapper, diskID)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		diskName, _ := disk.Alias()
+
+		// Preallocated disks cannot be sparsified
+		if sparse, ok := disk.Sparse(); ok && !sparse {
+			ui.Message(fmt.Sprintf("Skipping preallocated disk: %s (ID: %s)", diskName, diskID))
+			continue
+		}
+
+		sizeBefore, _ := disk.ActualSize()
+		ui.Message(fmt.Sprintf("Sparsifying disk: %s (ID: %s, actual size: %d bytes)", diskName, diskID, sizeBefore))
+
+		// The disk may still be OK right after the request, so follow the
+		// engine job instead of the disk status
+		correlationID := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+		err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			_, err := conn.SystemService().
+				DisksService().
+				DiskService(diskID).
+				Sparsify().
+				Header("Correlation-Id", correlationID).
+				Send()
+			return err
+		})
+		if err != nil {
+			err = fmt.Errorf("Error sparsifying disk %s: %s", diskName, err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+
+		if err := waitForJob(connWrapper, ui, state, "Sparsify job step", correlationID); err != nil {
+			err = fmt.Errorf("Error sparsifying disk %s: %s", diskName, err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+
+		diskStateChange := StateChangeConf{
+			Pending:   []string{string(ovirtsdk4.DISKSTATUS_LOCKED)},
+			Target:    []string{string(ovirtsdk4.DISKSTATUS_OK)},
+			Refresh:   DiskStateRefreshFuncWithWrapper(connWrapper, diskID),
+			StepState: state,
+		}
+		if _, err := WaitForState(&diskStateChange); err != nil {
+			err = fmt.Errorf("Error waiting for disk %s to return to OK state: %s", diskName, err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+
+		disk, err = s.getDisk(connWrapper, diskID)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		sizeAfter, _ := disk.ActualSize()
+		ui.Message(fmt.Sprintf("Sparsified disk: %s (actual size: %d -> %d bytes)", diskName, sizeBefore, sizeAfter))
+
+		sizesBefore[diskID] = strconv.FormatInt(sizeBefore, 10)
+		sizesAfter[diskID] = strconv.FormatInt(sizeAfter, 10)
+	}
+
+	putArtifactState(state, "sparsify_actual_size_before", sizesBefore)
+	putArtifactState(state, "sparsify_actual_size_after", sizesAfter)
+
+	ui.Say("Successfully sparsified VM disks")
+	return multistep.ActionContinue
+}
+
+func (s *stepSparsifyDisks) getDisk(connWrapper *ConnectionWrapper, diskID string) (*ovirtsdk4.Disk, error) {
+	var diskResp *ovirtsdk4.DiskServiceGetResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		diskResp, err = conn.SystemService().DisksService().DiskService(diskID).Get().Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting disk %s: %s", diskID, err)
+	}
+	return diskResp.MustDisk(), nil
+}
+
+func (s *stepSparsifyDisks) Cleanup(state multistep.StateBag) {
+	// Nothing to cleanup for this step
+}
@@ -2,7 +2,9 @@ package olvm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/packer"
@@ -12,6 +14,7 @@ import (
 type stepStopVM struct{}
 
 func (s *stepStopVM) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
 	ui := state.Get("ui").(packer.Ui)
 	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
 	vmID := state.Get("vm_id").(string)
@@ -46,8 +49,65 @@ func (s *stepStopVM) Run(ctx context.Context, state multistep.StateBag) multiste
 		return multistep.ActionContinue
 	}
 
-	// Only stop the VM if it's not already down
-	ui.Say(fmt.Sprintf("Stopping VM: %s...", vmID))
+	// Gracefully shut down the VM, either with the shutdown command over the
+	// communicator or with the engine shutdown action (ACPI/guest agent)
+	comm, hasComm := state.GetOk("communicator")
+	if config.ShutdownCommand != "" && hasComm {
+		ui.Say(fmt.Sprintf("Shutting down VM %s using shutdown_command...", vmID))
+		log.Printf("Executing shutdown command: %s", config.ShutdownCommand)
+
+		cmd := &packer.RemoteCmd{Command: config.ShutdownCommand}
+		if err := comm.(packer.Communicator).Start(ctx, cmd); err != nil {
+			err = fmt.Errorf("Error sending shutdown command: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	} else {
+		if config.ShutdownCommand != "" {
+			log.Printf("No communicator available for shutdown_command, using engine shutdown")
+		}
+		ui.Say(fmt.Sprintf("Shutting down VM %s using engine shutdown (ACPI/guest agent)...", vmID))
+
+		err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			_, err := conn.SystemService().
+				VmsService().
+				VmService(vmID).
+				Shutdown().
+				Send()
+			return err
+		})
+
+		if err != nil {
+			err = fmt.Errorf("Error shutting down VM: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	ui.Message(fmt.Sprintf("Waiting up to %s for VM to shut down: %s...", config.ShutdownTimeout, vmID))
+	shutdownStateChange := StateChangeConf{
+		Pending:   []string{string(ovirtsdk4.VMSTATUS_UP), string(ovirtsdk4.VMSTATUS_POWERING_DOWN)},
+		Target:    []string{string(ovirtsdk4.VMSTATUS_DOWN)},
+		Refresh:   VMStateRefreshFuncWithWrapper(connWrapper, vmID),
+		StepState: state,
+		Timeout:   config.ShutdownTimeout,
+	}
+	_, err = WaitForState(&shutdownStateChange)
+	if err == nil {
+		ui.Say(fmt.Sprintf("VM %s shut down gracefully", vmID))
+		return multistep.ActionContinue
+	}
+	if !errors.Is(err, errStateChangeTimeout) {
+		err := fmt.Errorf("Error waiting for VM (%s) to shut down: %s", vmID, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Fall back to a hard stop once the shutdown timeout has expired
+	ui.Say(fmt.Sprintf("VM %s did not shut down within %s, falling back to hard stop...", vmID, config.ShutdownTimeout))
 
 	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
 		_, err := conn.SystemService().
@@ -67,7 +127,7 @@ func (s *stepStopVM) Run(ctx context.Context, state multistep.StateBag) multiste
 
 	ui.Message(fmt.Sprintf("Waiting for VM to stop: %s...", vmID))
 	stateChange := StateChangeConf{
-		Pending:   []string{string(ovirtsdk4.VMSTATUS_UP)},
+		Pending:   []string{string(ovirtsdk4.VMSTATUS_UP), string(ovirtsdk4.VMSTATUS_POWERING_DOWN)},
 		Target:    []string{string(ovirtsdk4.VMSTATUS_DOWN)},
 		Refresh:   VMStateRefreshFuncWithWrapper(connWrapper, vmID),
 		StepState: state,
@@ -0,0 +1,148 @@
+package olvm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepWaitForGuestAgent struct{}
+
+// guestInfo holds the guest inventory reported by the guest agent
+type guestInfo struct {
+	OSDistribution string
+	OSVersion      string
+	KernelVersion  string
+	Hostname       string
+	IPAddresses    []string
+}
+
+// complete reports whether the guest agent has reported OS version, hostname and IPs
+func (g *guestInfo) complete() bool {
+	return (g.OSDistribution != "" || g.OSVersion != "") && g.Hostname != "" && len(g.IPAddresses) > 0
+}
+
+// stateData returns the guest inventory as artifact state entries
+func (g *guestInfo) stateData() map[string]interface{} {
+	return map[string]interface{}{
+		"guest_os_distribution": g.OSDistribution,
+		"guest_os_version":      g.OSVersion,
+		"guest_kernel_version":  g.KernelVersion,
+		"guest_hostname":        g.Hostname,
+		"guest_ip_addresses":    g.IPAddresses,
+	}
+}
+
+func (s *stepWaitForGuestAgent) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	connWrapper := state.Get("connWrapper").(*ConnectionWrapper)
+	vmID := state.Get("vm_id").(string)
+
+	// Skip if wait_for_guest_agent is not set
+	if !config.WaitForGuestAgent {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Waiting up to %s for the guest agent to report OS version, hostname and IPs...", config.GuestAgentTimeout))
+
+	stateChange := StateChangeConf{
+		Pending:   []string{"waiting"},
+		Target:    []string{"reported"},
+		Refresh:   s.guestInfoRefreshFunc(connWrapper, vmID),
+		StepState: state,
+		Timeout:   config.GuestAgentTimeout,
+	}
+	result, err := WaitForState(&stateChange)
+	if err != nil {
+		err = fmt.Errorf("Error waiting for guest agent on VM (%s): %s", vmID, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	info := result.(*guestInfo)
+	ui.Message(fmt.Sprintf("Guest OS: %s %s (kernel %s)", info.OSDistribution, info.OSVersion, info.KernelVersion))
+	ui.Message(fmt.Sprintf("Guest hostname: %s", info.Hostname))
+	ui.Message(fmt.Sprintf("Guest IP addresses: %s", strings.Join(info.IPAddresses, ", ")))
+
+	state.Put("guest_info", info)
+	for name, value := range info.stateData() {
+		putArtifactState(state, name, value)
+	}
+
+	return multistep.ActionContinue
+}
+
+// guestInfoRefreshFunc returns a StateRefreshFunc that reports "reported" once
+// the guest agent has provided the full guest inventory
+func (s *stepWaitForGuestAgent) guestInfoRefreshFunc(connWrapper *ConnectionWrapper, vmID string) StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		info := &guestInfo{}
+
+		var vmResp *ovirtsdk4.VmServiceGetResponse
+		err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			var err error
+			vmResp, err = conn.SystemService().VmsService().VmService(vmID).Get().Send()
+			return err
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		vm := vmResp.MustVm()
+		if fqdn, ok := vm.Fqdn(); ok {
+			info.Hostname = fqdn
+		}
+		if guestOS, ok := vm.GuestOperatingSystem(); ok {
+			info.OSDistribution, _ = guestOS.Distribution()
+			if version, ok := guestOS.Version(); ok {
+				info.OSVersion, _ = version.FullVersion()
+			}
+			if kernel, ok := guestOS.Kernel(); ok {
+				if version, ok := kernel.Version(); ok {
+					info.KernelVersion, _ = version.FullVersion()
+				}
+			}
+		}
+
+		var devicesResp *ovirtsdk4.VmReportedDevicesServiceListResponse
+		err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			var err error
+			devicesResp, err = conn.SystemService().VmsService().VmService(vmID).ReportedDevicesService().List().Send()
+			return err
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		if devices, ok := devicesResp.ReportedDevice(); ok {
+			for _, device := range devices.Slice() {
+				ips, ok := device.Ips()
+				if !ok {
+					continue
+				}
+				for _, ip := range ips.Slice() {
+					if address, ok := ip.Address(); ok {
+						info.IPAddresses = append(info.IPAddresses, address)
+					}
+				}
+			}
+		}
+
+		if !info.complete() {
+			log.Printf("Guest agent inventory incomplete: %+v", info)
+			return info, "waiting", nil
+		}
+		return info, "reported", nil
+	}
+}
+
+func (s *stepWaitForGuestAgent) Cleanup(state multistep.StateBag) {
+	// Nothing to cleanup for this step
+}
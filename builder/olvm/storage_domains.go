@@ -0,0 +1,34 @@
+package olvm
+
+import (
+	"fmt"
+	"log"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// findStorageDomainID returns the ID of the named storage domain
+func findStorageDomainID(connWrapper *ConnectionWrapper, storageDomainName string) (string, error) {
+	var sdsResp *ovirtsdk4.StorageDomainsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		sdsResp, err = conn.SystemService().StorageDomainsService().List().
+			Search(fmt.Sprintf("name=%s", storageDomainName)).
+			Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error searching storage domains: %s", err)
+	}
+
+	if storageDomains, ok := sdsResp.StorageDomains(); ok {
+		for _, sd := range storageDomains.Slice() {
+			if name, ok := sd.Name(); ok && name == storageDomainName {
+				log.Printf("Using storage domain: %s (ID: %s)", storageDomainName, sd.MustId())
+				return sd.MustId(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find storage domain '%s'", storageDomainName)
+}
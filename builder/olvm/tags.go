@@ -0,0 +1,103 @@
+package olvm
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// tagTemplateData holds the build variables available when interpolating
+// template_tags and vm_tags
+type tagTemplateData struct {
+	BuildName    string
+	VMName       string
+	TemplateName string
+	Cluster      string
+}
+
+// renderTags interpolates the given tags with the build variables, dropping
+// empty and duplicate results
+func renderTags(config *Config, tags []string) ([]string, error) {
+	ctx := config.ctx
+	ctx.Data = &tagTemplateData{
+		BuildName:    config.PackerBuildName,
+		VMName:       config.VMName,
+		TemplateName: config.DestinationTemplateName,
+		Cluster:      config.Cluster,
+	}
+
+	var rendered []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		name, err := interpolate.Render(tag, &ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Error interpolating tag '%s': %s", tag, err)
+		}
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		rendered = append(rendered, name)
+	}
+	return rendered, nil
+}
+
+// findOrCreateTag returns the ID of the named tag, creating it if it does not exist
+func findOrCreateTag(connWrapper *ConnectionWrapper, tagName string) (string, error) {
+	var tagsResp *ovirtsdk4.TagsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		tagsResp, err = conn.SystemService().TagsService().List().Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error getting tags: %s", err)
+	}
+
+	if tags, ok := tagsResp.Tags(); ok {
+		for _, tag := range tags.Slice() {
+			if name, ok := tag.Name(); ok && name == tagName {
+				return tag.MustId(), nil
+			}
+		}
+	}
+
+	log.Printf("Creating tag: %s", tagName)
+	var addResp *ovirtsdk4.TagsServiceAddResponse
+	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		addResp, err = conn.SystemService().TagsService().Add().
+			Tag(ovirtsdk4.NewTagBuilder().Name(tagName).MustBuild()).
+			Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error creating tag %s: %s", tagName, err)
+	}
+	return addResp.MustTag().MustId(), nil
+}
+
+// assignTags assigns the named tags, creating them on demand, through the
+// assigned tags service returned by tagsService
+func assignTags(connWrapper *ConnectionWrapper, tagNames []string, tagsService func(conn *ovirtsdk4.Connection) *ovirtsdk4.AssignedTagsService) error {
+	for _, tagName := range tagNames {
+		tagID, err := findOrCreateTag(connWrapper, tagName)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Assigning tag: %s (ID: %s)", tagName, tagID)
+		err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			_, err := tagsService(conn).Add().
+				Tag(ovirtsdk4.NewTagBuilder().Id(tagID).MustBuild()).
+				Send()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Error assigning tag %s: %s", tagName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package olvm
+
+import (
+	"errors"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// TemplatePermissionConfig grants a role on the produced template to a user or group
+type TemplatePermissionConfig struct {
+	User  string `mapstructure:"user"`
+	Group string `mapstructure:"group"`
+	Role  string `mapstructure:"role"`
+}
+
+// Prepare performs basic validation on the TemplatePermissionConfig
+func (c *TemplatePermissionConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if c.User == "" && c.Group == "" {
+		errs = append(errs, errors.New("template_permissions requires user or group"))
+	}
+	if c.User != "" && c.Group != "" {
+		errs = append(errs, errors.New("Conflict: Set either user or group in template_permissions"))
+	}
+	if c.Role == "" {
+		errs = append(errs, errors.New("template_permissions requires role"))
+	}
+
+	return errs
+}
+
+// principal returns a description of the user or group for messages
+func (c *TemplatePermissionConfig) principal() string {
+	if c.User != "" {
+		return "user " + c.User
+	}
+	return "group " + c.Group
+}
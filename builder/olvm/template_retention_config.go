@@ -0,0 +1,42 @@
+package olvm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// TemplateRetentionConfig selects old templates to prune after a successful build
+type TemplateRetentionConfig struct {
+	NamePrefix string        `mapstructure:"name_prefix"`
+	Tag        string        `mapstructure:"tag"`
+	KeepLast   int           `mapstructure:"keep_last"`
+	MaxAge     time.Duration `mapstructure:"max_age"`
+	DryRun     bool          `mapstructure:"dry_run"`
+}
+
+// Prepare performs basic validation on the TemplateRetentionConfig
+func (c *TemplateRetentionConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if c.NamePrefix == "" && c.Tag == "" {
+		errs = append(errs, errors.New("template_retention requires name_prefix or tag"))
+	}
+	if strings.ContainsAny(c.NamePrefix, "*\"") {
+		errs = append(errs, fmt.Errorf("Invalid template_retention name_prefix: %s", c.NamePrefix))
+	}
+	if c.KeepLast < 0 {
+		errs = append(errs, fmt.Errorf("template_retention keep_last must not be negative, got %d", c.KeepLast))
+	}
+	if c.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("template_retention max_age must not be negative, got %s", c.MaxAge))
+	}
+	if c.KeepLast == 0 && c.MaxAge == 0 {
+		errs = append(errs, errors.New("template_retention requires keep_last or max_age"))
+	}
+
+	return errs
+}
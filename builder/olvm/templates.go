@@ -0,0 +1,305 @@
+package olvm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// findTemplatesByName returns all versions of the templates with exactly the
+// given name, ordered by descending version number
+func findTemplatesByName(connWrapper *ConnectionWrapper, templateName string) ([]*ovirtsdk4.Template, error) {
+	var tpsResp *ovirtsdk4.TemplatesServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		tpsResp, err = conn.SystemService().TemplatesService().List().
+			Search(fmt.Sprintf("name=%s", templateName)).
+			Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error searching templates: %s", err)
+	}
+
+	var templates []*ovirtsdk4.Template
+	if tpSlice, ok := tpsResp.Templates(); ok {
+		for _, tp := range tpSlice.Slice() {
+			if name, ok := tp.Name(); ok && name == templateName {
+				templates = append(templates, tp)
+			}
+		}
+	}
+
+	sort.SliceStable(templates, func(i, j int) bool {
+		return templateVersionNumber(templates[i]) > templateVersionNumber(templates[j])
+	})
+
+	return templates, nil
+}
+
+// findSourceTemplate returns the template with the given name and version
+// number, or its latest version when the version number is 0, optionally only
+// considering templates in the named data center. Templates without a cluster
+// are always considered since their data center is unknown. Matches in
+// several version chains are an error listing the candidates.
+func findSourceTemplate(connWrapper *ConnectionWrapper, templateName string, versionNumber int, dataCenterName string) (*ovirtsdk4.Template, error) {
+	templates, err := findTemplatesByName(connWrapper, templateName)
+	if err != nil {
+		return nil, err
+	}
+	clusterDataCenterNames, err := findClusterDataCenterNames(connWrapper)
+	if err != nil {
+		return nil, err
+	}
+	templateDataCenterName := func(template *ovirtsdk4.Template) string {
+		if cluster, ok := template.Cluster(); ok {
+			if id, ok := cluster.Id(); ok {
+				return clusterDataCenterNames[id]
+			}
+		}
+		return ""
+	}
+
+	// Templates are ordered by descending version, so the first template of
+	// each version chain is its latest version
+	var candidates []*ovirtsdk4.Template
+	chains := make(map[string]bool)
+	for _, tp := range templates {
+		// Templates without a cluster are kept since their data center is unknown
+		if tpDataCenterName := templateDataCenterName(tp); dataCenterName != "" && tpDataCenterName != "" && tpDataCenterName != dataCenterName {
+			continue
+		}
+		if versionNumber == 0 {
+			chain := templateBaseID(tp)
+			if chain == "" {
+				chain = tp.MustId()
+			}
+			if !chains[chain] {
+				chains[chain] = true
+				candidates = append(candidates, tp)
+			}
+		} else if templateVersionNumber(tp) == int64(versionNumber) {
+			candidates = append(candidates, tp)
+		}
+	}
+
+	version := "latest"
+	if versionNumber != 0 {
+		version = strconv.Itoa(versionNumber)
+	}
+	scope := ""
+	if dataCenterName != "" {
+		scope = fmt.Sprintf(" in data center '%s'", dataCenterName)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("Could not find template '%s' with version '%s'%s", templateName, version, scope)
+	case 1:
+		return candidates[0], nil
+	default:
+		var descriptions []string
+		for _, tp := range candidates {
+			dc := templateDataCenterName(tp)
+			if dc == "" {
+				dc = "unknown"
+			}
+			descriptions = append(descriptions, fmt.Sprintf("%s (version %d, data center %s)", tp.MustId(), templateVersionNumber(tp), dc))
+		}
+		return nil, fmt.Errorf("Found %d templates '%s' with version '%s'%s, set data_center or source_template_id to select one: %s",
+			len(candidates), templateName, version, scope, strings.Join(descriptions, ", "))
+	}
+}
+
+// findClusterTemplatesByName returns all versions of the templates with
+// exactly the given name in the data center of the named cluster, ordered by
+// descending version number. Templates without a cluster are skipped since
+// their data center is unknown.
+func findClusterTemplatesByName(connWrapper *ConnectionWrapper, clusterName, templateName string) ([]*ovirtsdk4.Template, error) {
+	clusterID, err := findClusterID(connWrapper, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	dataCenterID, err := findClusterDataCenterID(connWrapper, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	var clustersResp *ovirtsdk4.ClustersServiceListResponse
+	err = connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		clustersResp, err = conn.SystemService().DataCentersService().DataCenterService(dataCenterID).ClustersService().List().Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting clusters of data center %s: %s", dataCenterID, err)
+	}
+	dataCenterClusters := make(map[string]bool)
+	if clusters, ok := clustersResp.Clusters(); ok {
+		for _, cluster := range clusters.Slice() {
+			dataCenterClusters[cluster.MustId()] = true
+		}
+	}
+
+	templates, err := findTemplatesByName(connWrapper, templateName)
+	if err != nil {
+		return nil, err
+	}
+	var clusterTemplates []*ovirtsdk4.Template
+	for _, tp := range templates {
+		if cluster, ok := tp.Cluster(); ok && dataCenterClusters[cluster.MustId()] {
+			clusterTemplates = append(clusterTemplates, tp)
+		}
+	}
+	return clusterTemplates, nil
+}
+
+// templateVersionNumber returns the version number of a template, or 1 if
+// the template does not report one
+func templateVersionNumber(template *ovirtsdk4.Template) int64 {
+	if version, ok := template.Version(); ok {
+		if versionNumber, ok := version.VersionNumber(); ok {
+			return versionNumber
+		}
+	}
+	return 1
+}
+
+// removeTemplate deletes a template and waits until the engine no longer reports it
+func removeTemplate(connWrapper *ConnectionWrapper, state multistep.StateBag, templateID string) error {
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		_, err := conn.SystemService().TemplatesService().TemplateService(templateID).Remove().Send()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Error removing template %s: %s", templateID, err)
+	}
+
+	// Wait for the template to disappear
+	templateStateChange := StateChangeConf{
+		Pending:   []string{"ok", "locked", "image_locked"},
+		Target:    []string{""},
+		Refresh:   TemplateStateRefreshFuncWithWrapper(connWrapper, templateID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&templateStateChange); err != nil {
+		return fmt.Errorf("Error waiting for template %s to be removed: %s", templateID, err)
+	}
+	return nil
+}
+
+// checkDeleteProtection fails if any of the given templates is delete protected
+func checkDeleteProtection(templates []*ovirtsdk4.Template) error {
+	for _, tp := range templates {
+		if deleteProtected, ok := tp.DeleteProtected(); ok && deleteProtected {
+			return fmt.Errorf("Existing template %s is delete protected and cannot be replaced", tp.MustId())
+		}
+	}
+	return nil
+}
+
+// removeTemplates deletes the given template versions, newest first, so that
+// the base template is removed after all of its sub-versions
+func removeTemplates(connWrapper *ConnectionWrapper, ui packer.Ui, state multistep.StateBag, templates []*ovirtsdk4.Template) error {
+	for _, tp := range templates {
+		templateID := tp.MustId()
+		ui.Say(fmt.Sprintf("Removing replaced template version %d (ID: %s)...", templateVersionNumber(tp), templateID))
+
+		if err := removeTemplate(connWrapper, state, templateID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timestampedName returns the template name with a timestamp suffix
+func timestampedName(name string) string {
+	return fmt.Sprintf("%s-%s", name, time.Now().Format("20060102150405"))
+}
+
+// renameBaseTemplate renames the base template among the given templates. The
+// engine carries the new name over to all of its sub-versions.
+func renameBaseTemplate(connWrapper *ConnectionWrapper, ui packer.Ui, templates []*ovirtsdk4.Template, newName string) error {
+	for _, tp := range templates {
+		if templateVersionNumber(tp) != 1 {
+			continue
+		}
+		templateID := tp.MustId()
+		ui.Say(fmt.Sprintf("Renaming existing template '%s' (ID: %s) to '%s'...", tp.MustName(), templateID, newName))
+
+		err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			_, err := conn.SystemService().TemplatesService().TemplateService(templateID).Update().
+				Template(ovirtsdk4.NewTemplateBuilder().Name(newName).MustBuild()).
+				Send()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Error renaming existing template %s: %s", templateID, err)
+		}
+	}
+	return nil
+}
+
+// discardTemplate removes a new template when the build fails, so that it is
+// not left behind half configured. A template still being created is waited
+// for first, since a locked template cannot be removed. The waits are not
+// tied to the step state, so the template is also removed when the build is
+// cancelled.
+func discardTemplate(connWrapper *ConnectionWrapper, ui packer.Ui, config *Config, templateID string) {
+	ui.Say(fmt.Sprintf("Removing incomplete template %s...", templateID))
+
+	templateStateChange := StateChangeConf{
+		Pending: []string{"locked", "image_locked"},
+		Target:  []string{"ok", ""},
+		Refresh: TemplateStateRefreshFuncWithWrapper(connWrapper, templateID),
+		Timeout: config.JobTimeout,
+	}
+	if _, err := WaitForState(&templateStateChange); err != nil {
+		ui.Error(fmt.Sprintf("Error waiting for template %s to be unlocked: %s", templateID, err))
+		return
+	}
+
+	if config.TemplateDeleteProtected {
+		err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			_, err := conn.SystemService().TemplatesService().TemplateService(templateID).Update().
+				Template(ovirtsdk4.NewTemplateBuilder().DeleteProtected(false).MustBuild()).
+				Send()
+			return err
+		})
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error removing delete protection from template %s: %s", templateID, err))
+			return
+		}
+	}
+
+	if err := removeTemplate(connWrapper, nil, templateID); err != nil {
+		ui.Error(err.Error())
+	}
+}
+
+// buildFailed reports whether the build was halted or cancelled, which the
+// cleanup of the template steps uses to undo their changes
+func buildFailed(state multistep.StateBag) bool {
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	return cancelled || halted
+}
+
+// templateBaseID returns the ID of the base template of a sub-version, or an
+// empty string for base templates
+func templateBaseID(template *ovirtsdk4.Template) string {
+	if version, ok := template.Version(); ok {
+		if base, ok := version.BaseTemplate(); ok {
+			if id, ok := base.Id(); ok && id != template.MustId() {
+				return id
+			}
+		}
+	}
+	return ""
+}
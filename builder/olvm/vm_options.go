@@ -0,0 +1,71 @@
+package olvm
+
+import (
+	"sort"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// diskCacheCustomProperty is the engine custom property that controls the
+// disk cache mode of a VM. There is no counterpart for the disk I/O mode: the
+// API only exposes the number of I/O threads, and the engine itself picks
+// "native" or "threads" from the storage type of each disk.
+const diskCacheCustomProperty = "viodiskcache"
+
+// buildOperatingSystem returns the operating system settings (OS type and boot
+// order) for the VM and template, or nil if none are configured
+func buildOperatingSystem(config *Config) (*ovirtsdk4.OperatingSystem, error) {
+	if config.OSType == "" && len(config.BootDevices) == 0 {
+		return nil, nil
+	}
+
+	osBuilder := ovirtsdk4.NewOperatingSystemBuilder()
+	if config.OSType != "" {
+		osBuilder.Type(config.OSType)
+	}
+	if len(config.BootDevices) > 0 {
+		var devices []ovirtsdk4.BootDevice
+		for _, device := range config.BootDevices {
+			devices = append(devices, ovirtsdk4.BootDevice(device))
+		}
+		osBuilder.Boot(
+			ovirtsdk4.NewBootBuilder().
+				Devices(devices).
+				MustBuild(),
+		)
+	}
+
+	return osBuilder.Build()
+}
+
+// buildCustomProperties returns the engine custom properties for the VM and
+// template, including the disk cache mode, sorted by name
+func buildCustomProperties(config *Config) ([]*ovirtsdk4.CustomProperty, error) {
+	properties := make(map[string]string, len(config.CustomProperties)+1)
+	for name, value := range config.CustomProperties {
+		properties[name] = value
+	}
+	if config.DiskCacheMode != "" {
+		properties[diskCacheCustomProperty] = config.DiskCacheMode
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var customProperties []*ovirtsdk4.CustomProperty
+	for _, name := range names {
+		property, err := ovirtsdk4.NewCustomPropertyBuilder().
+			Name(name).
+			Value(properties[name]).
+			Build()
+		if err != nil {
+			return nil, err
+		}
+		customProperties = append(customProperties, property)
+	}
+
+	return customProperties, nil
+}
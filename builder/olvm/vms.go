@@ -0,0 +1,178 @@
+package olvm
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// findVM returns the VM with the given ID, or the VM with the given name when
+// no ID is set
+func findVM(connWrapper *ConnectionWrapper, vmName, vmID string) (*ovirtsdk4.Vm, error) {
+	if vmID != "" {
+		var vmResp *ovirtsdk4.VmServiceGetResponse
+		err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+			var err error
+			vmResp, err = conn.SystemService().VmsService().VmService(vmID).Get().Send()
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Could not find VM with ID '%s': %s", vmID, err)
+		}
+		return vmResp.MustVm(), nil
+	}
+
+	var vmsResp *ovirtsdk4.VmsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		vmsResp, err = conn.SystemService().VmsService().List().
+			Search(fmt.Sprintf("name=%s", vmName)).
+			Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error searching VMs: %s", err)
+	}
+
+	if vms, ok := vmsResp.Vms(); ok {
+		for _, vm := range vms.Slice() {
+			if name, ok := vm.Name(); ok && name == vmName {
+				log.Printf("Using VM id: %s", vm.MustId())
+				return vm, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("Could not find VM '%s'", vmName)
+}
+
+// findVMSnapshot returns the snapshot of the given VM whose ID or description
+// matches, including the VM configuration stored with the snapshot. The active
+// snapshot is never matched since it is the current state of the VM.
+func findVMSnapshot(connWrapper *ConnectionWrapper, vmID, snapshot string) (*ovirtsdk4.Snapshot, error) {
+	var snapshotsResp *ovirtsdk4.SnapshotsServiceListResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		snapshotsResp, err = conn.SystemService().
+			VmsService().
+			VmService(vmID).
+			SnapshotsService().
+			List().
+			AllContent(true).
+			Send()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting snapshots of VM %s: %s", vmID, err)
+	}
+
+	var matches []*ovirtsdk4.Snapshot
+	if snapshots, ok := snapshotsResp.Snapshots(); ok {
+		for _, sn := range snapshots.Slice() {
+			if snapshotType, ok := sn.SnapshotType(); ok && snapshotType == ovirtsdk4.SNAPSHOTTYPE_ACTIVE {
+				continue
+			}
+			if id, ok := sn.Id(); ok && id == snapshot {
+				return sn, nil
+			}
+			if description, ok := sn.Description(); ok && description == snapshot {
+				matches = append(matches, sn)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("Could not find snapshot '%s' of VM %s", snapshot, vmID)
+	case 1:
+		log.Printf("Using snapshot id: %s", matches[0].MustId())
+		return matches[0], nil
+	default:
+		var ids []string
+		for _, sn := range matches {
+			ids = append(ids, sn.MustId())
+		}
+		sort.Strings(ids)
+		return nil, fmt.Errorf("Found %d snapshots of VM %s with description '%s', use the snapshot ID instead: %s",
+			len(matches), vmID, snapshot, strings.Join(ids, ", "))
+	}
+}
+
+// createVMSnapshot takes a snapshot of the VM disks and configuration,
+// without memory, and returns its ID once the engine job finished
+func createVMSnapshot(connWrapper *ConnectionWrapper, ui packer.Ui, state multistep.StateBag, vmID, description string) (string, error) {
+	correlationID := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	var snapshotResp *ovirtsdk4.SnapshotsServiceAddResponse
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		var err error
+		snapshotResp, err = conn.SystemService().
+			VmsService().
+			VmService(vmID).
+			SnapshotsService().
+			Add().
+			Header("Correlation-Id", correlationID).
+			Snapshot(ovirtsdk4.NewSnapshotBuilder().
+				Description(description).
+				PersistMemorystate(false).
+				MustBuild()).
+			Send()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error creating snapshot of VM %s: %s", vmID, err)
+	}
+	snapshotID := snapshotResp.MustSnapshot().MustId()
+	log.Printf("Creating snapshot %s of VM %s (correlation ID: %s)", snapshotID, vmID, correlationID)
+
+	if err := waitForJob(connWrapper, ui, state, "Snapshot job step", correlationID); err != nil {
+		return "", fmt.Errorf("Error creating snapshot of VM %s: %s", vmID, err)
+	}
+	return snapshotID, nil
+}
+
+// removeVMSnapshot removes the snapshot of the VM and waits for the engine
+// job to finish
+func removeVMSnapshot(connWrapper *ConnectionWrapper, ui packer.Ui, state multistep.StateBag, vmID, snapshotID string) error {
+	correlationID := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	log.Printf("Removing snapshot %s of VM %s (correlation ID: %s)", snapshotID, vmID, correlationID)
+	err := connWrapper.ExecuteWithReconnect(func(conn *ovirtsdk4.Connection) error {
+		_, err := conn.SystemService().
+			VmsService().
+			VmService(vmID).
+			SnapshotsService().
+			SnapshotService(snapshotID).
+			Remove().
+			Header("Correlation-Id", correlationID).
+			Send()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Error removing snapshot %s of VM %s: %s", snapshotID, vmID, err)
+	}
+
+	if err := waitForJob(connWrapper, ui, state, "Snapshot removal job step", correlationID); err != nil {
+		return fmt.Errorf("Error removing snapshot %s of VM %s: %s", snapshotID, vmID, err)
+	}
+	return nil
+}
+
+// vmCPUCount returns the number of vCPUs (sockets times cores) of the given
+// CPU, defaulting to 1
+func vmCPUCount(cpu *ovirtsdk4.Cpu) int {
+	if cpu != nil {
+		if topology, ok := cpu.Topology(); ok {
+			sockets, _ := topology.Sockets()
+			cores, _ := topology.Cores()
+			if sockets*cores > 0 {
+				return int(sockets * cores)
+			}
+		}
+	}
+	return 1
+}